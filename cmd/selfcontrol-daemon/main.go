@@ -3,14 +3,14 @@ package main
 import (
 	"fmt"
 	"os"
-	"time"
 
-	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/daemon"
 	"github.com/phil/selfcontrol/internal/state"
 )
 
 func main() {
-	// This daemon runs in the background and checks for expired sessions
+	// This daemon runs in the background, unblocks expired sessions and
+	// re-applies blocking rules that were tampered with
 	// It should be run with root privileges
 
 	if os.Geteuid() != 0 {
@@ -20,36 +20,9 @@ func main() {
 
 	fmt.Printf("SelfControl Daemon started\n")
 	fmt.Printf("State file: %s\n", state.GetStatePath())
-	fmt.Println("Monitoring for expired sessions every 10 seconds...")
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	d := daemon.New()
 
-	for range ticker.C {
-		// Load current state
-		st, err := state.Load()
-		if err != nil {
-			fmt.Printf("Error loading state: %v\n", err)
-			continue
-		}
-
-		// Check if session expired
-		if st.ActiveSession != nil && !st.IsSessionActive() {
-			fmt.Printf("Session expired at %s, unblocking...\n", st.ActiveSession.EndTime)
-
-			// Unblock
-			if err := blocker.Unblock(); err != nil {
-				fmt.Printf("Error unblocking: %v\n", err)
-				continue
-			}
-
-			// End session
-			st.EndSession()
-			if err := state.Save(st); err != nil {
-				fmt.Printf("Error saving state: %v\n", err)
-			}
-
-			fmt.Println("Successfully unblocked websites")
-		}
-	}
+	fmt.Println("Watching for session changes...")
+	d.Run()
 }
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/blocklist"
+	"github.com/phil/selfcontrol/internal/control"
+	"github.com/phil/selfcontrol/internal/state"
+	"github.com/phil/selfcontrol/internal/timer"
+)
+
+// listFlag collects a flag that may be repeated or comma-separated
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// newFlagSet returns a flag set with a usage line and description
+func newFlagSet(name, usage, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: selfcontrol "+usage)
+		fmt.Fprintln(fs.Output(), description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runAdd adds URLs or patterns to the current profile
+func runAdd(args []string) error {
+	fs := newFlagSet("add", "add <url>...", "Adds URLs or patterns to the current profile.")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no URLs given")
+	}
+
+	svc := control.Connect()
+	for _, url := range fs.Args() {
+		if err := svc.AddURL(url); err != nil {
+			return err
+		}
+		fmt.Printf("Added %s\n", url)
+	}
+	return nil
+}
+
+// runRemove removes URLs or patterns from the current profile
+func runRemove(args []string) error {
+	fs := newFlagSet("remove", "remove <url>...", "Removes URLs or patterns from the current profile.")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no URLs given")
+	}
+
+	svc := control.Connect()
+	st, err := svc.Status()
+	if err != nil {
+		return err
+	}
+	for _, url := range fs.Args() {
+		if !slices.Contains(st.Current().URLs, url) {
+			return fmt.Errorf("%s is not in profile %q", url, st.CurrentProfile)
+		}
+	}
+
+	if err := svc.RemoveURLs(fs.Args()); err != nil {
+		return err
+	}
+	for _, url := range fs.Args() {
+		fmt.Printf("Removed %s\n", url)
+	}
+	return nil
+}
+
+// runImport adds the domains of blocklist files to the current profile
+func runImport(args []string) error {
+	fs := newFlagSet("import", "import [--dry-run] <file>...",
+		"Imports hosts files, plain domain lists and Adblock Plus ||domain^ rules into the current profile; - reads standard input.")
+	dryRun := fs.Bool("dry-run", false, "only show what would be imported")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no files given")
+	}
+
+	var sources []*blocklist.Source
+	for _, path := range fs.Args() {
+		src, err := blocklist.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, src)
+	}
+
+	svc := control.Connect()
+	st, err := svc.Status()
+	if err != nil {
+		return err
+	}
+	urls := blocklist.Merge(sources, st.Current().URLs)
+
+	for _, src := range sources {
+		fmt.Printf("%s: %s\n", src.Name, src.Summary())
+	}
+	if *dryRun || len(urls) == 0 {
+		return nil
+	}
+
+	if err := svc.AddURLs(urls); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d URLs into profile %q\n", len(urls), st.CurrentProfile)
+	return nil
+}
+
+// runExport writes profiles in a format that can be shared and imported
+func runExport(args []string) error {
+	fs := newFlagSet("export", "export [--format txt|json|hosts] [--profile name]... [--all] [-o file]",
+		"Exports the URLs of the current profile, or of other profiles, to a file or standard output.")
+	format := fs.String("format", blocklist.FormatText, "output format: "+strings.Join(blocklist.Formats, ", "))
+	var names listFlag
+	fs.Var(&names, "profile", "profile to export (repeatable, default: current)")
+	all := fs.Bool("all", false, "export all profiles")
+	output := fs.String("o", "", "file to write instead of standard output")
+	fs.Parse(args)
+
+	st, err := control.Connect().Status()
+	if err != nil {
+		return err
+	}
+
+	var profiles []*state.Profile
+	switch {
+	case *all:
+		profiles = st.Profiles
+	case len(names) == 0:
+		profiles = []*state.Profile{st.Current()}
+	default:
+		for _, name := range names {
+			p := st.Profile(name)
+			if p == nil {
+				return fmt.Errorf("no profile named %q", name)
+			}
+			profiles = append(profiles, p)
+		}
+	}
+
+	if *output == "" {
+		return blocklist.Write(os.Stdout, *format, blocklist.Lists(profiles))
+	}
+	if err := blocklist.WriteFile(*output, *format, blocklist.Lists(profiles)); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %s to %s\n", plural(len(profiles), "profile"), *output)
+	return nil
+}
+
+// plural formats a count of things
+func plural(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// runList prints the URLs of the current profile, or of other profiles
+func runList(args []string) error {
+	fs := newFlagSet("list", "list [--profile name] [--all]", "Lists the URLs and patterns of a profile.")
+	var profiles listFlag
+	fs.Var(&profiles, "profile", "profile to list (repeatable, default: current)")
+	all := fs.Bool("all", false, "list all profiles")
+	fs.Parse(args)
+
+	st, err := control.Connect().Status()
+	if err != nil {
+		return err
+	}
+
+	if *all {
+		profiles = nil
+		for _, p := range st.Profiles {
+			profiles = append(profiles, p.Name)
+		}
+	}
+	if len(profiles) == 0 {
+		// Plain list for scripts
+		for _, url := range st.Current().URLs {
+			fmt.Println(url)
+		}
+		return nil
+	}
+
+	for i, name := range profiles {
+		p := st.Profile(name)
+		if p == nil {
+			return fmt.Errorf("no profile named %q", name)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s:\n", p.Name)
+		for _, url := range p.URLs {
+			fmt.Printf("  %s\n", url)
+		}
+	}
+	return nil
+}
+
+// runStart starts a blocking session
+func runStart(args []string) error {
+	fs := newFlagSet("start", "start [--duration 90m] [--profile name]...",
+		"Starts a blocking session; the duration defaults to the profile's default duration.")
+	duration := fs.String("duration", "", "how long to block, e.g. 90m, 2h30m, 'until 17:30' or 'until friday 17:00'")
+	var profiles listFlag
+	fs.Var(&profiles, "profile", "profile to enforce (repeatable, default: current)")
+	fs.Parse(args)
+
+	svc := control.Connect()
+
+	var d timer.Duration
+	if *duration != "" {
+		var err error
+		if d, err = timer.Parse(*duration, time.Now().In(timer.LocalZone())); err != nil {
+			return err
+		}
+	} else {
+		st, err := svc.Status()
+		if err != nil {
+			return err
+		}
+		name := st.CurrentProfile
+		if len(profiles) > 0 {
+			name = profiles[0]
+		}
+		if p := st.Profile(name); p != nil {
+			d = timer.Duration{Label: timer.FormatDuration(p.Default()), Duration: p.Default(), End: time.Now().Add(p.Default())}
+		}
+		if d.Duration == 0 {
+			return fmt.Errorf("no --duration given and profile %q has no default duration", name)
+		}
+	}
+
+	if err := svc.StartSessionUntil(d.End, d.Label, profiles); err != nil {
+		return err
+	}
+	fmt.Printf("Blocking until %s\n", d.End.Format("2006-01-02 15:04:05 MST"))
+	return nil
+}
+
+// runMode shows or changes whether a profile is a blocklist or an allowlist
+func runMode(args []string) error {
+	fs := newFlagSet("mode", "mode [--profile name] [block|allow]",
+		"Shows or sets the mode of a profile: block its URLs, or allow only them and block everything else.")
+	profile := fs.String("profile", "", "profile to show or change (default: current)")
+	fs.Parse(args)
+
+	svc := control.Connect()
+	st, err := svc.Status()
+	if err != nil {
+		return err
+	}
+	name := *profile
+	if name == "" {
+		name = st.CurrentProfile
+	}
+	p := st.Profile(name)
+	if p == nil {
+		return fmt.Errorf("no profile named %q", name)
+	}
+
+	if fs.NArg() == 0 {
+		if p.Allowlist() {
+			fmt.Println(state.ModeAllow)
+		} else {
+			fmt.Println(state.ModeBlock)
+		}
+		return nil
+	}
+
+	mode := fs.Arg(0)
+	if err := svc.SetProfileMode(name, mode); err != nil {
+		return err
+	}
+	if mode == state.ModeAllow {
+		fmt.Printf("Profile %q is now an allowlist: sessions block everything else\n", name)
+	} else {
+		fmt.Printf("Profile %q is now a blocklist\n", name)
+	}
+	return nil
+}
+
+// runSubscribe makes a profile follow a remote blocklist
+func runSubscribe(args []string) error {
+	fs := newFlagSet("subscribe", "subscribe [--profile name] [--refresh 24h] <url>",
+		"Subscribes a profile to a remote blocklist; the daemon fetches it regularly and blocks its domains.")
+	profile := fs.String("profile", "", "profile to subscribe (default: current)")
+	refresh := fs.Duration("refresh", state.DefaultRefresh, "how often the list is fetched, at least "+timer.FormatDuration(state.MinRefresh))
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one list URL")
+	}
+
+	svc := control.Connect()
+	name, err := profileName(svc, *profile)
+	if err != nil {
+		return err
+	}
+	if err := svc.Subscribe(name, fs.Arg(0), *refresh); err != nil {
+		return err
+	}
+	fmt.Printf("Profile %q is subscribed to %s, refreshed every %s\n", name, fs.Arg(0), timer.FormatDuration(*refresh))
+	if _, ok := svc.(*control.Client); !ok {
+		fmt.Println("The list is fetched by selfcontrol-daemon, which isn't running")
+	}
+	return nil
+}
+
+// profileName returns the named profile, or the current one if name is empty
+func profileName(svc control.Service, name string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+	st, err := svc.Status()
+	if err != nil {
+		return "", err
+	}
+	return st.CurrentProfile, nil
+}
+
+// runUnsubscribe stops a profile from following a remote blocklist
+func runUnsubscribe(args []string) error {
+	fs := newFlagSet("unsubscribe", "unsubscribe [--profile name] <url>",
+		"Unsubscribes a profile from a remote blocklist.")
+	profile := fs.String("profile", "", "profile to unsubscribe (default: current)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one list URL")
+	}
+
+	svc := control.Connect()
+	name, err := profileName(svc, *profile)
+	if err != nil {
+		return err
+	}
+	if err := svc.Unsubscribe(name, fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Printf("Profile %q is no longer subscribed to %s\n", name, fs.Arg(0))
+	return nil
+}
+
+// runSubscriptions lists the subscriptions of all profiles and their caches
+func runSubscriptions(args []string) error {
+	fs := newFlagSet("subscriptions", "subscriptions", "Lists the remote blocklists profiles are subscribed to.")
+	fs.Parse(args)
+
+	subs, err := control.Connect().Subscriptions()
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		fmt.Println("No subscriptions")
+		return nil
+	}
+
+	fmt.Printf("%-12s  %-8s  %-8s  %-19s  %s\n", "Profile", "Refresh", "Domains", "Updated", "URL")
+	for _, sub := range subs {
+		domains, updated := "-", "never"
+		if sub.Cache != nil {
+			domains = fmt.Sprint(sub.Cache.Domains)
+			if !sub.Cache.FetchedAt.IsZero() {
+				updated = sub.Cache.FetchedAt.Local().Format("2006-01-02 15:04:05")
+			}
+		}
+		fmt.Printf("%-12s  %-8s  %-8s  %-19s  %s\n",
+			sub.Profile, timer.FormatDuration(sub.Subscription.Interval()), domains, updated, sub.Subscription.URL)
+		if sub.Cache != nil && sub.Cache.Error != "" {
+			fmt.Printf("  last refresh failed, using the cached copy: %s\n", sub.Cache.Error)
+		}
+	}
+	return nil
+}
+
+// runHistory prints past sessions, or the recorded events
+func runHistory(args []string) error {
+	fs := newFlagSet("history", "history [--events]", "Lists past sessions.")
+	events := fs.Bool("events", false, "list tamper events instead of sessions")
+	fs.Parse(args)
+
+	svc := control.Connect()
+
+	if *events {
+		list, err := svc.History()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("No events recorded")
+			return nil
+		}
+		for _, e := range list {
+			fmt.Printf("%s  %-8s  %s\n", e.Time.Format("2006-01-02 15:04:05"), e.Kind, e.Detail)
+		}
+		return nil
+	}
+
+	sessions, err := svc.Sessions()
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No sessions recorded")
+		return nil
+	}
+
+	now := time.Now()
+	fmt.Printf("%-19s  %-12s  %-10s  %s\n", "Started", "Focused", "Ended", "Profiles")
+	for _, e := range sessions {
+		ended := e.EndReason
+		if ended == "" {
+			ended = "running"
+		}
+		fmt.Printf("%-19s  %-12s  %-10s  %s\n",
+			e.Start.Local().Format("2006-01-02 15:04:05"),
+			timer.FormatDuration(e.Focused(now)),
+			ended,
+			strings.Join(e.Profiles, ", "))
+	}
+	return nil
+}
@@ -5,15 +5,28 @@ import (
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phil/selfcontrol/internal/control"
 	"github.com/phil/selfcontrol/internal/ui"
 )
 
 func main() {
+	// Subcommands run without the interactive UI
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Talk to the daemon if it is running, otherwise change the state directly
+	svc := control.Connect()
+
 	// Check if running with appropriate permissions
-	// Note: On most systems, modifying /etc/hosts requires root
-	if os.Geteuid() != 0 {
-		fmt.Println("⚠️  Warning: Not running as root.")
-		fmt.Println("You may need to run with sudo to modify /etc/hosts:")
+	// Note: Without the daemon, modifying /etc/hosts requires root
+	if _, local := svc.(*control.Local); local && os.Geteuid() != 0 {
+		fmt.Println("⚠️  Warning: The daemon is not running and you are not root.")
+		fmt.Println("Start selfcontrol-daemon, or run with sudo to modify /etc/hosts:")
 		fmt.Println("  sudo selfcontrol")
 		fmt.Println()
 		fmt.Println("Continuing anyway... (errors will be shown if permissions are insufficient)")
@@ -21,7 +34,7 @@ func main() {
 	}
 
 	// Create UI model
-	m, err := ui.New()
+	m, err := ui.New(svc)
 	if err != nil {
 		fmt.Printf("Error initializing: %v\n", err)
 		os.Exit(1)
@@ -36,3 +49,62 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// usage describes the subcommands
+const usage = `Usage: selfcontrol [command] [flags]
+
+Without a command, the interactive interface is started.
+
+Commands:
+  add <url>...           Add URLs or patterns to the current profile
+  remove <url>...        Remove URLs or patterns from the current profile
+  export                 Export profiles as txt, json or hosts files
+  import <file>...       Import hosts, domain or Adblock lists into the current profile
+  list                   List the URLs of a profile
+  start                  Start a blocking session
+  mode [block|allow]     Show or set whether a profile is a blocklist or an allowlist
+  subscribe <url>        Subscribe a profile to a remote blocklist
+  unsubscribe <url>      Unsubscribe a profile from a remote blocklist
+  subscriptions          List subscribed blocklists and when they were updated
+  status                 Show the active session
+  history                List past sessions
+  restore [--list|<id>]  Restore /etc/hosts from a backup
+
+Run "selfcontrol <command> -h" for the flags of a command.`
+
+// runCommand dispatches a non-interactive subcommand
+func runCommand(name string, args []string) error {
+	switch name {
+	case "add":
+		return runAdd(args)
+	case "remove":
+		return runRemove(args)
+	case "import":
+		return runImport(args)
+	case "export":
+		return runExport(args)
+	case "list":
+		return runList(args)
+	case "start":
+		return runStart(args)
+	case "mode":
+		return runMode(args)
+	case "subscribe":
+		return runSubscribe(args)
+	case "unsubscribe":
+		return runUnsubscribe(args)
+	case "subscriptions":
+		return runSubscriptions(args)
+	case "status":
+		return runStatus(args)
+	case "history":
+		return runHistory(args)
+	case "restore":
+		return runRestore(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", name, usage)
+	}
+}
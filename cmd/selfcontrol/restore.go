@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// runRestore lists the hosts file backups or restores one of them
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	list := fs.Bool("list", false, "list available backups")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: selfcontrol restore [--list | <id>]")
+		fmt.Fprintln(fs.Output(), "Restores /etc/hosts from a backup taken before blocking started.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	hosts := blocker.NewHostsBlocker()
+
+	if *list || fs.NArg() == 0 {
+		backups, err := hosts.Backups()
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups available")
+			return nil
+		}
+
+		fmt.Printf("%-22s  %-19s  %s\n", "ID", "Taken", "Size")
+		for _, b := range backups {
+			fmt.Printf("%-22s  %-19s  %d bytes\n", b.ID, b.Time.Format("2006-01-02 15:04:05"), b.Size)
+		}
+		return nil
+	}
+
+	id := fs.Arg(0)
+	if err := hosts.Restore(id); err != nil {
+		return err
+	}
+	fmt.Printf("Restored /etc/hosts from backup %s\n", id)
+
+	// A restored backup has no blocking rules, so re-apply them if a session
+	// is running; allowlist sessions don't use the hosts file
+	st, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	if st.IsSessionActive() && !st.ActiveSession.Allowlist() && (st.Backend == "" || st.Backend == blocker.BackendHosts) {
+		if err := hosts.Block(st.SessionURLs()); err != nil {
+			return fmt.Errorf("failed to re-apply blocking for the active session: %w", err)
+		}
+		fmt.Println("Re-applied blocking rules for the active session")
+	}
+
+	return nil
+}
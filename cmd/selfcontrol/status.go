@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/control"
+	"github.com/phil/selfcontrol/internal/state"
+	"github.com/phil/selfcontrol/internal/timer"
+)
+
+// statusReport is the status printed by the status command, also available
+// as JSON and to --format templates
+type statusReport struct {
+	Active           bool       `json:"active"`
+	Profile          string     `json:"profile"`
+	Profiles         []string   `json:"profiles"`
+	Duration         string     `json:"duration,omitempty"`
+	StartTime        *time.Time `json:"start_time,omitempty"`
+	EndTime          *time.Time `json:"end_time,omitempty"`
+	TimeZone         string     `json:"time_zone,omitempty"`
+	RemainingSeconds int64      `json:"remaining_seconds"`
+	Remaining        string     `json:"remaining"`
+
+	// URLCount is the number of URLs and patterns the session enforces,
+	// before wildcards are expanded; Mode is "allow" when they are the only
+	// ones allowed rather than blocked
+	URLCount int    `json:"url_count"`
+	Mode     string `json:"mode,omitempty"`
+
+	// Blocked is what the blocker reports, nil if it couldn't be asked;
+	// Consistent is set when that agrees with the session state
+	Blocked      *bool  `json:"blocked"`
+	Consistent   bool   `json:"consistent"`
+	BlockerError string `json:"blocker_error,omitempty"`
+}
+
+// runStatus prints the state of the current session
+func runStatus(args []string) error {
+	fs := newFlagSet("status", "status [--json | --format template]", "Shows the active session.")
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	format := fs.String("format", "", "print the status with a Go template, e.g. '{{if .Active}}{{.Remaining}}{{end}}'")
+	fs.Parse(args)
+
+	report, err := status(control.Connect())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *asJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+
+	case *format != "":
+		tmpl, err := template.New("status").Parse(*format)
+		if err != nil {
+			return fmt.Errorf("invalid format: %w", err)
+		}
+		if err := tmpl.Execute(os.Stdout, report); err != nil {
+			return err
+		}
+		fmt.Println()
+		return nil
+	}
+
+	if !report.Active {
+		fmt.Println("No active session")
+		fmt.Printf("Profile: %s\n", report.Profile)
+	} else {
+		fmt.Println("Session: active")
+		fmt.Printf("Profiles: %s\n", strings.Join(report.Profiles, ", "))
+		fmt.Printf("Duration: %s\n", report.Duration)
+		fmt.Printf("Started: %s\n", report.StartTime.Format("2006-01-02 15:04:05"))
+		fmt.Printf("Ends: %s (%s remaining)\n", report.EndTime.Format("2006-01-02 15:04:05 MST"), report.Remaining)
+		if report.Mode == state.ModeAllow {
+			fmt.Println("Mode: allowlist, everything else is blocked")
+			fmt.Printf("Allowed URLs: %d\n", report.URLCount)
+		} else {
+			fmt.Printf("Blocked URLs: %d\n", report.URLCount)
+		}
+	}
+
+	switch {
+	case report.Blocked == nil:
+		fmt.Printf("⚠️  Could not check the blocking rules: %s\n", report.BlockerError)
+	case !report.Consistent && report.Active:
+		fmt.Println("⚠️  The blocking rules are missing; the daemon will re-apply them")
+	case !report.Consistent:
+		fmt.Println("⚠️  Blocking rules are in place although no session is active")
+	}
+	return nil
+}
+
+// status collects the status report from the service
+func status(svc control.Service) (*statusReport, error) {
+	st, err := svc.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &statusReport{
+		Active:   st.IsSessionActive(),
+		Profile:  st.CurrentProfile,
+		Profiles: []string{},
+	}
+	if report.Active {
+		session := st.ActiveSession
+		remaining := st.TimeRemaining()
+
+		report.Profiles = session.Profiles
+		report.Duration = session.Duration
+		report.StartTime = &session.StartTime
+		end := session.EndTime.In(session.Zone())
+		report.EndTime = &end
+		report.TimeZone = session.Location
+		report.RemainingSeconds = int64(remaining.Seconds())
+		report.Remaining = timer.FormatDuration(remaining)
+		report.URLCount = len(st.SessionURLs())
+		report.Mode = state.ModeBlock
+		if session.Allowlist() {
+			report.Mode = state.ModeAllow
+		}
+	}
+
+	blocked, err := svc.Blocked()
+	if err != nil {
+		report.BlockerError = err.Error()
+	} else {
+		report.Blocked = &blocked
+		report.Consistent = blocked == report.Active
+	}
+
+	return report, nil
+}
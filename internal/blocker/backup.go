@@ -0,0 +1,127 @@
+package blocker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/fsutil"
+)
+
+const (
+	backupDir    = "/var/lib/selfcontrol/backups"
+	backupPrefix = "hosts-"
+	backupIDTime = "20060102-150405.000"
+
+	// DefaultBackupKeep is the number of hosts file backups kept by default
+	DefaultBackupKeep = 10
+)
+
+// Backup is a snapshot of the hosts file taken before blocking started
+type Backup struct {
+	ID   string
+	Time time.Time
+	Size int64
+	path string
+}
+
+// Backups lists the available hosts file backups, newest first
+func (h *HostsBlocker) Backups() ([]Backup, error) {
+	entries, err := os.ReadDir(h.BackupDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		id, ok := strings.CutPrefix(entry.Name(), backupPrefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		t, err := time.ParseInLocation(backupIDTime, id, time.Local)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, Backup{
+			ID:   id,
+			Time: t,
+			Size: info.Size(),
+			path: filepath.Join(h.BackupDir, entry.Name()),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+
+	return backups, nil
+}
+
+// Restore replaces the hosts file with the backup with the given ID
+func (h *HostsBlocker) Restore(id string) error {
+	backups, err := h.Backups()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range backups {
+		if b.ID != id {
+			continue
+		}
+
+		content, err := os.ReadFile(b.path)
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+		if err := fsutil.WriteFileAtomic(h.Path, content, 0644); err != nil {
+			return fmt.Errorf("failed to write hosts file (are you running with sudo?): %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no backup with id %q", id)
+}
+
+// backup snapshots the hosts file content and prunes old backups
+func (h *HostsBlocker) backup(content []byte) error {
+	if h.BackupDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(h.BackupDir, 0755); err != nil {
+		return err
+	}
+
+	name := backupPrefix + time.Now().Format(backupIDTime)
+	if err := fsutil.WriteFileAtomic(filepath.Join(h.BackupDir, name), content, 0644); err != nil {
+		return err
+	}
+
+	backups, err := h.Backups()
+	if err != nil {
+		return err
+	}
+
+	keep := h.BackupKeep
+	if keep <= 0 {
+		keep = DefaultBackupKeep
+	}
+	for _, b := range backups[min(keep, len(backups)):] {
+		os.Remove(b.path)
+	}
+
+	return nil
+}
@@ -1,106 +1,107 @@
 package blocker
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"os"
 	"strings"
 )
 
-const (
-	hostsFile  = "/etc/hosts"
-	beginMarker = "# BEGIN SELFCONTROL-TUI"
-	endMarker   = "# END SELFCONTROL-TUI"
-)
+// Blocker applies and removes blocking rules for a list of URLs or patterns
+type Blocker interface {
+	// Block replaces any existing rules with rules for the given URLs
+	Block(urls []string) error
 
-// Block adds blocking rules to /etc/hosts
-func Block(urls []string) error {
-	// First, ensure we're not already blocking
-	if err := Unblock(); err != nil {
-		return fmt.Errorf("failed to clear existing blocks: %w", err)
-	}
+	// Unblock removes all rules previously added by Block
+	Unblock() error
 
-	// Read current hosts file
-	content, err := os.ReadFile(hostsFile)
-	if err != nil {
-		return fmt.Errorf("failed to read hosts file: %w", err)
-	}
+	// IsBlocked checks if our blocking rules are currently in place
+	IsBlocked() (bool, error)
 
-	// Expand wildcards to actual hostnames
-	hosts := expandWildcards(urls)
+	// Verify checks that the rules in place are exactly the ones Block
+	// applies for the given URLs, so tampering can be detected
+	Verify(urls []string) (bool, error)
+}
+
+// Allower is implemented by backends that can also block everything except
+// a list of URLs, which the hosts file can't express
+type Allower interface {
+	// Allow replaces any existing rules with rules that block everything
+	// but the given URLs
+	Allow(urls []string) error
 
-	// Build blocking rules
-	var blockingRules strings.Builder
-	blockingRules.WriteString("\n")
-	blockingRules.WriteString(beginMarker)
-	blockingRules.WriteString("\n")
+	// VerifyAllowed checks that the rules in place are exactly the ones
+	// Allow applies for the given URLs
+	VerifyAllowed(urls []string) (bool, error)
+}
 
-	for _, host := range hosts {
-		// Block both with and without www
-		blockingRules.WriteString(fmt.Sprintf("127.0.0.1 %s\n", host))
+// Preparer is implemented by backends that do slow work, such as resolving
+// names, before applying rules; Prepare is called before the state is
+// locked, so the work doesn't hold up other clients
+type Preparer interface {
+	Prepare(urls []string)
+}
 
-		// Also block IPv6
-		blockingRules.WriteString(fmt.Sprintf("::1 %s\n", host))
+// Prepare lets b do the slow work of applying rules for urls ahead of time
+func Prepare(b Blocker, urls []string) {
+	if p, ok := b.(Preparer); ok {
+		p.Prepare(urls)
 	}
+}
 
-	blockingRules.WriteString(endMarker)
-	blockingRules.WriteString("\n")
+// ErrAllowUnsupported is returned when an allowlist session can't be
+// enforced with the configured backend
+var ErrAllowUnsupported = errors.New("allowlist sessions need the nftables backend or the DNS sinkhole; the hosts file can't block everything")
 
-	// Append to hosts file
-	newContent := string(content) + blockingRules.String()
+// CanAllow reports whether allowlist sessions can be enforced by b, or by
+// the DNS sinkhole if sinkhole is set
+func CanAllow(b Blocker, sinkhole bool) bool {
+	_, ok := b.(Allower)
+	return ok || sinkhole
+}
 
-	if err := os.WriteFile(hostsFile, []byte(newContent), 0644); err != nil {
-		return fmt.Errorf("failed to write hosts file (are you running with sudo?): %w", err)
+// Apply applies the rules of a session: the URLs are blocked, or with allow
+// everything else is
+// Backends that can't allow leave the allowlist to the DNS sinkhole and
+// remove their rules instead.
+func Apply(b Blocker, urls []string, allow bool) error {
+	if !allow {
+		return b.Block(urls)
 	}
-
-	return nil
+	if a, ok := b.(Allower); ok {
+		return a.Allow(urls)
+	}
+	return b.Unblock()
 }
 
-// Unblock removes blocking rules from /etc/hosts
-func Unblock() error {
-	// Read current hosts file
-	file, err := os.Open(hostsFile)
-	if err != nil {
-		return fmt.Errorf("failed to open hosts file: %w", err)
+// Check verifies the rules that Apply puts in place
+func Check(b Blocker, urls []string, allow bool) (bool, error) {
+	if !allow {
+		return b.Verify(urls)
 	}
-	defer file.Close()
-
-	var newContent strings.Builder
-	scanner := bufio.NewScanner(file)
-	inBlockSection := false
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Check if we're entering our block section
-		if strings.TrimSpace(line) == beginMarker {
-			inBlockSection = true
-			continue
-		}
-
-		// Check if we're leaving our block section
-		if strings.TrimSpace(line) == endMarker {
-			inBlockSection = false
-			continue
-		}
-
-		// Only write lines that are not in our block section
-		if !inBlockSection {
-			newContent.WriteString(line)
-			newContent.WriteString("\n")
-		}
+	if a, ok := b.(Allower); ok {
+		return a.VerifyAllowed(urls)
 	}
+	blocked, err := b.IsBlocked()
+	return !blocked, err
+}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read hosts file: %w", err)
-	}
+// Backend names accepted by New
+const (
+	BackendHosts    = "hosts"
+	BackendNftables = "nftables"
+)
 
-	// Write back the modified content
-	if err := os.WriteFile(hostsFile, []byte(newContent.String()), 0644); err != nil {
-		return fmt.Errorf("failed to write hosts file (are you running with sudo?): %w", err)
+// New returns the blocker for the named backend
+// An empty name selects the default /etc/hosts backend
+func New(backend string) (Blocker, error) {
+	switch backend {
+	case "", BackendHosts:
+		return NewHostsBlocker(), nil
+	case BackendNftables:
+		return sharedNftables, nil
+	default:
+		return nil, fmt.Errorf("unknown blocking backend %q", backend)
 	}
-
-	return nil
 }
 
 // expandWildcards converts wildcard patterns to actual hostnames
@@ -162,13 +163,3 @@ func expandWildcards(urls []string) []string {
 
 	return result
 }
-
-// IsBlocked checks if our blocking rules are currently in place
-func IsBlocked() (bool, error) {
-	content, err := os.ReadFile(hostsFile)
-	if err != nil {
-		return false, fmt.Errorf("failed to read hosts file: %w", err)
-	}
-
-	return strings.Contains(string(content), beginMarker), nil
-}
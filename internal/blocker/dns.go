@@ -0,0 +1,353 @@
+package blocker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Sinkhole defaults
+const (
+	DefaultSinkholeListen   = "127.0.0.1:53"
+	DefaultSinkholeUpstream = "1.1.1.1:53"
+)
+
+// upstreamTimeout bounds how long a forwarded query may take
+const upstreamTimeout = 5 * time.Second
+
+// Sinkhole is a local DNS forwarder that answers queries for blocked names
+// itself and forwards everything else to an upstream resolver
+//
+// Unlike the hosts backend it matches patterns against every queried name,
+// so wildcards don't depend on guessing subdomains or TLDs. In allowlist
+// mode it answers for every name except the matching ones.
+type Sinkhole struct {
+	// Listen is the UDP and TCP address to serve on
+	Listen string
+
+	// Upstream is the resolver that receives queries for allowed names
+	Upstream string
+
+	mu       sync.RWMutex
+	patterns []pattern
+	allow    bool
+	udp      net.PacketConn
+	tcp      net.Listener
+}
+
+// NewSinkhole returns a sinkhole, filling in defaults for empty addresses
+func NewSinkhole(listen, upstream string) *Sinkhole {
+	if listen == "" {
+		listen = DefaultSinkholeListen
+	}
+	if upstream == "" {
+		upstream = DefaultSinkholeUpstream
+	}
+	return &Sinkhole{Listen: listen, Upstream: upstream}
+}
+
+// Block makes the sinkhole answer for every name matching the URLs
+func (s *Sinkhole) Block(urls []string) error {
+	patterns := compilePatterns(urls)
+
+	s.mu.Lock()
+	s.patterns, s.allow = patterns, false
+	s.mu.Unlock()
+	return nil
+}
+
+// Allow makes the sinkhole answer for every name except those matching the
+// URLs
+func (s *Sinkhole) Allow(urls []string) error {
+	patterns := compilePatterns(urls)
+
+	s.mu.Lock()
+	s.patterns, s.allow = patterns, true
+	s.mu.Unlock()
+	return nil
+}
+
+// Unblock forwards all queries again
+func (s *Sinkhole) Unblock() error {
+	s.mu.Lock()
+	s.patterns, s.allow = nil, false
+	s.mu.Unlock()
+	return nil
+}
+
+// IsBlocked checks if any names are being answered by the sinkhole
+func (s *Sinkhole) IsBlocked() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.allow || len(s.patterns) > 0, nil
+}
+
+// Verify checks that the sinkhole answers for exactly the given URLs
+func (s *Sinkhole) Verify(urls []string) (bool, error) {
+	return s.verify(urls, false), nil
+}
+
+// VerifyAllowed checks that the sinkhole answers for everything except the
+// given URLs
+func (s *Sinkhole) VerifyAllowed(urls []string) (bool, error) {
+	return s.verify(urls, true), nil
+}
+
+// verify compares the mode and patterns in use with the expected ones
+func (s *Sinkhole) verify(urls []string, allow bool) bool {
+	expected := compilePatterns(urls)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.allow == allow && slices.EqualFunc(s.patterns, expected, func(a, b pattern) bool {
+		return slices.Equal(a, b)
+	})
+}
+
+// ListenAndServe serves DNS over UDP and TCP until Close is called
+func (s *Sinkhole) ListenAndServe() error {
+	udp, err := net.ListenPacket("udp", s.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for DNS on %s: %w", s.Listen, err)
+	}
+	tcp, err := net.Listen("tcp", s.Listen)
+	if err != nil {
+		udp.Close()
+		return fmt.Errorf("failed to listen for DNS on %s: %w", s.Listen, err)
+	}
+
+	s.mu.Lock()
+	s.udp, s.tcp = udp, tcp
+	s.mu.Unlock()
+
+	go s.serveTCP(tcp)
+	return s.serveUDP(udp)
+}
+
+// Close stops the listeners
+func (s *Sinkhole) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	if s.udp != nil {
+		errs = append(errs, s.udp.Close())
+	}
+	if s.tcp != nil {
+		errs = append(errs, s.tcp.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// serveUDP answers datagrams until the connection is closed
+func (s *Sinkhole) serveUDP(conn net.PacketConn) error {
+	buf := make([]byte, 65535)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		query := make([]byte, n)
+		copy(query, buf[:n])
+
+		go func() {
+			resp, err := s.handle(query, "udp")
+			if err != nil {
+				return
+			}
+			conn.WriteTo(resp, addr)
+		}()
+	}
+}
+
+// serveTCP accepts connections until the listener is closed
+func (s *Sinkhole) serveTCP(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveTCPConn(conn)
+	}
+}
+
+// serveTCPConn answers length-prefixed queries on a single connection
+func (s *Sinkhole) serveTCPConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		conn.SetDeadline(time.Now().Add(upstreamTimeout))
+
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			return
+		}
+
+		resp, err := s.handle(query, "tcp")
+		if err != nil {
+			return
+		}
+
+		if err := writeTCPMessage(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// handle answers a raw query, either from the sinkhole or from upstream
+func (s *Sinkhole) handle(query []byte, network string) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	blocked := matchHost(s.patterns, question.Name.String()) != s.allow
+	s.mu.RUnlock()
+
+	if blocked {
+		return sinkholeResponse(header, question)
+	}
+
+	resp, err := s.forward(query, network)
+	if err != nil {
+		// Tell the client right away instead of letting it time out
+		return failureResponse(header, question)
+	}
+	return resp, nil
+}
+
+// forward relays a query to the upstream resolver over the same transport
+func (s *Sinkhole) forward(query []byte, network string) ([]byte, error) {
+	conn, err := net.DialTimeout(network, s.Upstream, upstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(upstreamTimeout))
+
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(conn)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// sinkholeResponse answers A and AAAA queries with the unspecified address
+// and everything else with NXDOMAIN
+func sinkholeResponse(query dnsmessage.Header, question dnsmessage.Question) ([]byte, error) {
+	header := dnsmessage.Header{
+		ID:                 query.ID,
+		Response:           true,
+		OpCode:             query.OpCode,
+		RecursionDesired:   query.RecursionDesired,
+		RecursionAvailable: true,
+	}
+	if question.Type != dnsmessage.TypeA && question.Type != dnsmessage.TypeAAAA {
+		header.RCode = dnsmessage.RCodeNameError
+	}
+
+	builder := dnsmessage.NewBuilder(nil, header)
+	builder.EnableCompression()
+
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	answer := dnsmessage.ResourceHeader{
+		Name:  question.Name,
+		Class: dnsmessage.ClassINET,
+		TTL:   60,
+	}
+	switch question.Type {
+	case dnsmessage.TypeA:
+		if err := builder.AResource(answer, dnsmessage.AResource{}); err != nil {
+			return nil, err
+		}
+	case dnsmessage.TypeAAAA:
+		if err := builder.AAAAResource(answer, dnsmessage.AAAAResource{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
+
+// failureResponse answers a query with SERVFAIL
+func failureResponse(query dnsmessage.Header, question dnsmessage.Question) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 query.ID,
+		Response:           true,
+		OpCode:             query.OpCode,
+		RecursionDesired:   query.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              dnsmessage.RCodeServerFailure,
+	})
+
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+// readTCPMessage reads one length-prefixed DNS message
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeTCPMessage writes one length-prefixed DNS message
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,305 @@
+package blocker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// upstreamAddr is what the stand-in upstream resolver answers
+var upstreamAddr = [4]byte{93, 184, 216, 34}
+
+// startUpstream runs a resolver on loopback that answers every A query with
+// upstreamAddr, over UDP and TCP on the same port
+func startUpstream(t *testing.T) string {
+	t.Helper()
+
+	var udp net.PacketConn
+	var tcp net.Listener
+	for i := 0; ; i++ {
+		var err error
+		if udp, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		if tcp, err = net.Listen("tcp", udp.LocalAddr().String()); err == nil {
+			break
+		}
+		udp.Close()
+		if i == 10 {
+			t.Fatalf("no free port for UDP and TCP: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp, err := upstreamAnswer(buf[:n]); err == nil {
+				udp.WriteTo(resp, addr)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				query, err := readTCPMessage(conn)
+				if err != nil {
+					return
+				}
+				if resp, err := upstreamAnswer(query); err == nil {
+					writeTCPMessage(conn, resp)
+				}
+			}()
+		}
+	}()
+
+	return udp.LocalAddr().String()
+}
+
+// upstreamAnswer answers a query with upstreamAddr
+func upstreamAnswer(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true})
+	builder.StartQuestions()
+	builder.Question(question)
+	builder.StartAnswers()
+	builder.AResource(dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 300},
+		dnsmessage.AResource{A: upstreamAddr})
+	return builder.Finish()
+}
+
+// buildQuery returns a query for name and type
+func buildQuery(t *testing.T, name string, typ dnsmessage.Type) []byte {
+	t.Helper()
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 4242, RecursionDesired: true})
+	builder.StartQuestions()
+	if err := builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  typ,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	query, err := builder.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query
+}
+
+// parseResponse returns the header and answers of a response
+func parseResponse(t *testing.T, resp []byte) (dnsmessage.Header, []dnsmessage.Resource) {
+	t.Helper()
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if msg.Header.ID != 4242 || !msg.Header.Response {
+		t.Fatalf("response header = %+v", msg.Header)
+	}
+	return msg.Header, msg.Answers
+}
+
+func TestSinkholeBlocked(t *testing.T) {
+	s := NewSinkhole("", startUpstream(t))
+	s.Block([]string{"*.linkedin.*"})
+
+	tests := []struct {
+		typ   dnsmessage.Type
+		rcode dnsmessage.RCode
+		body  dnsmessage.ResourceBody
+	}{
+		{dnsmessage.TypeA, dnsmessage.RCodeSuccess, &dnsmessage.AResource{}},
+		{dnsmessage.TypeAAAA, dnsmessage.RCodeSuccess, &dnsmessage.AAAAResource{}},
+		{dnsmessage.TypeMX, dnsmessage.RCodeNameError, nil},
+		{dnsmessage.TypeTXT, dnsmessage.RCodeNameError, nil},
+	}
+	for _, tt := range tests {
+		resp, err := s.handle(buildQuery(t, "static.linkedin.co.uk.", tt.typ), "udp")
+		if err != nil {
+			t.Fatalf("%s: handle: %v", tt.typ, err)
+		}
+		header, answers := parseResponse(t, resp)
+		if header.RCode != tt.rcode {
+			t.Errorf("%s: rcode = %s, want %s", tt.typ, header.RCode, tt.rcode)
+		}
+		if tt.body == nil {
+			if len(answers) != 0 {
+				t.Errorf("%s: answers = %v, want none", tt.typ, answers)
+			}
+			continue
+		}
+		if len(answers) != 1 || answers[0].Body.GoString() != tt.body.GoString() {
+			t.Errorf("%s: answers = %v, want %v", tt.typ, answers, tt.body)
+		}
+	}
+}
+
+func TestSinkholeForwards(t *testing.T) {
+	upstream := startUpstream(t)
+	s := NewSinkhole("", upstream)
+	s.Block([]string{"linkedin.com"})
+
+	for _, network := range []string{"udp", "tcp"} {
+		resp, err := s.handle(buildQuery(t, "example.org.", dnsmessage.TypeA), network)
+		if err != nil {
+			t.Fatalf("%s: handle: %v", network, err)
+		}
+		header, answers := parseResponse(t, resp)
+		if header.RCode != dnsmessage.RCodeSuccess || len(answers) != 1 {
+			t.Fatalf("%s: response = %+v %v", network, header, answers)
+		}
+		if a, ok := answers[0].Body.(*dnsmessage.AResource); !ok || a.A != upstreamAddr {
+			t.Errorf("%s: answer = %v, want the upstream's %v", network, answers[0].Body, upstreamAddr)
+		}
+	}
+}
+
+func TestSinkholeAllowlist(t *testing.T) {
+	s := NewSinkhole("", startUpstream(t))
+	s.Allow([]string{"wikipedia.org"})
+
+	tests := []struct {
+		name string
+		want [4]byte
+	}{
+		{"en.wikipedia.org.", upstreamAddr},
+		{"reddit.com.", [4]byte{}},
+	}
+	for _, tt := range tests {
+		resp, err := s.handle(buildQuery(t, tt.name, dnsmessage.TypeA), "udp")
+		if err != nil {
+			t.Fatalf("%s: handle: %v", tt.name, err)
+		}
+		_, answers := parseResponse(t, resp)
+		if len(answers) != 1 || answers[0].Body.(*dnsmessage.AResource).A != tt.want {
+			t.Errorf("%s: answers = %v, want %v", tt.name, answers, tt.want)
+		}
+	}
+}
+
+func TestSinkholeUpstreamFailure(t *testing.T) {
+	// A port nobody listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s := NewSinkhole("", addr)
+	for _, network := range []string{"udp", "tcp"} {
+		start := time.Now()
+		resp, err := s.handle(buildQuery(t, "example.org.", dnsmessage.TypeA), network)
+		if err != nil {
+			t.Fatalf("%s: handle: %v", network, err)
+		}
+		header, _ := parseResponse(t, resp)
+		if header.RCode != dnsmessage.RCodeServerFailure {
+			t.Errorf("%s: rcode = %s, want SERVFAIL", network, header.RCode)
+		}
+		if elapsed := time.Since(start); elapsed > upstreamTimeout+time.Second {
+			t.Errorf("%s: failure took %s", network, elapsed)
+		}
+	}
+}
+
+func TestSinkholeServe(t *testing.T) {
+	s := NewSinkhole("", startUpstream(t))
+	s.Block([]string{"reddit.com"})
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	defer tcp.Close()
+	go s.serveUDP(udp)
+	go s.serveTCP(tcp)
+
+	query := buildQuery(t, "old.reddit.com.", dnsmessage.TypeA)
+
+	// UDP
+	conn, err := net.Dial("udp", udp.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write(query)
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("udp: %v", err)
+	}
+	if _, answers := parseResponse(t, buf[:n]); len(answers) != 1 || answers[0].Body.(*dnsmessage.AResource).A != [4]byte{} {
+		t.Errorf("udp: answers = %v, want 0.0.0.0", answers)
+	}
+
+	// TCP
+	tconn, err := net.Dial("tcp", tcp.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tconn.Close()
+	tconn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := writeTCPMessage(tconn, query); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := readTCPMessage(tconn)
+	if err != nil {
+		t.Fatalf("tcp: %v", err)
+	}
+	if _, answers := parseResponse(t, resp); len(answers) != 1 || answers[0].Body.(*dnsmessage.AResource).A != [4]byte{} {
+		t.Errorf("tcp: answers = %v, want 0.0.0.0", answers)
+	}
+}
+
+func TestSinkholeVerify(t *testing.T) {
+	s := NewSinkhole("", "")
+	s.Block([]string{"reddit.com", "*.linkedin.*"})
+
+	if ok, _ := s.Verify([]string{"reddit.com", "*.linkedin.*"}); !ok {
+		t.Error("Verify of the blocked URLs failed")
+	}
+	if ok, _ := s.Verify([]string{"reddit.com"}); ok {
+		t.Error("Verify of other URLs succeeded")
+	}
+	if ok, _ := s.VerifyAllowed([]string{"reddit.com", "*.linkedin.*"}); ok {
+		t.Error("VerifyAllowed of a blocklist succeeded")
+	}
+
+	s.Unblock()
+	if blocked, _ := s.IsBlocked(); blocked {
+		t.Error("IsBlocked after Unblock")
+	}
+}
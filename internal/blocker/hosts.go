@@ -0,0 +1,209 @@
+package blocker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phil/selfcontrol/internal/fsutil"
+)
+
+const (
+	hostsFile   = "/etc/hosts"
+	beginMarker = "# BEGIN SELFCONTROL-TUI"
+	endMarker   = "# END SELFCONTROL-TUI"
+)
+
+// HostsBlocker blocks websites by redirecting them to localhost in a hosts file
+type HostsBlocker struct {
+	// Path is the hosts file to modify, normally /etc/hosts
+	Path string
+
+	// BackupDir receives a snapshot of the hosts file before blocking
+	// starts; backups are disabled if it is empty
+	BackupDir string
+
+	// BackupKeep is the number of backups to keep, DefaultBackupKeep if zero
+	BackupKeep int
+}
+
+// NewHostsBlocker returns a blocker for the system hosts file
+func NewHostsBlocker() *HostsBlocker {
+	return &HostsBlocker{
+		Path:       hostsFile,
+		BackupDir:  backupDir,
+		BackupKeep: DefaultBackupKeep,
+	}
+}
+
+// Block adds blocking rules to the hosts file
+func (h *HostsBlocker) Block(urls []string) error {
+	// Snapshot the pristine hosts file before the first block of a session
+	blocked, err := h.IsBlocked()
+	if err != nil {
+		return err
+	}
+	if !blocked {
+		pristine, err := os.ReadFile(h.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read hosts file: %w", err)
+		}
+		if err := h.backup(pristine); err != nil {
+			return fmt.Errorf("failed to back up hosts file: %w", err)
+		}
+	}
+
+	// First, ensure we're not already blocking
+	if err := h.Unblock(); err != nil {
+		return fmt.Errorf("failed to clear existing blocks: %w", err)
+	}
+
+	// Read current hosts file
+	content, err := os.ReadFile(h.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	// Build blocking rules
+	var blockingRules strings.Builder
+	blockingRules.WriteString("\n")
+	blockingRules.WriteString(beginMarker)
+	blockingRules.WriteString("\n")
+
+	for _, rule := range hostsRules(urls) {
+		blockingRules.WriteString(rule)
+		blockingRules.WriteString("\n")
+	}
+
+	blockingRules.WriteString(endMarker)
+	blockingRules.WriteString("\n")
+
+	// Append to hosts file
+	newContent := string(content) + blockingRules.String()
+
+	if err := fsutil.WriteFileAtomic(h.Path, []byte(newContent), 0644); err != nil {
+		return fmt.Errorf("failed to write hosts file (are you running with sudo?): %w", err)
+	}
+
+	return nil
+}
+
+// Unblock removes blocking rules from the hosts file
+func (h *HostsBlocker) Unblock() error {
+	// Read current hosts file
+	file, err := os.Open(h.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open hosts file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	inBlockSection := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Check if we're entering our block section
+		if strings.TrimSpace(line) == beginMarker {
+			inBlockSection = true
+
+			// Drop the blank line Block puts before the section
+			if n := len(lines); n > 0 && lines[n-1] == "" {
+				lines = lines[:n-1]
+			}
+			continue
+		}
+
+		// Check if we're leaving our block section
+		if strings.TrimSpace(line) == endMarker {
+			inBlockSection = false
+			continue
+		}
+
+		// Only write lines that are not in our block section
+		if !inBlockSection {
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var newContent strings.Builder
+	for _, line := range lines {
+		newContent.WriteString(line)
+		newContent.WriteString("\n")
+	}
+
+	// Write back the modified content
+	if err := fsutil.WriteFileAtomic(h.Path, []byte(newContent.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write hosts file (are you running with sudo?): %w", err)
+	}
+
+	return nil
+}
+
+// Verify checks that the rules between our markers are exactly the ones
+// Block would write for urls
+func (h *HostsBlocker) Verify(urls []string) (bool, error) {
+	content, err := os.ReadFile(h.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var actual []string
+	found, inBlockSection := false, false
+	for _, line := range strings.Split(string(content), "\n") {
+		switch strings.TrimSpace(line) {
+		case beginMarker:
+			if found {
+				// A second block section was added by someone else
+				return false, nil
+			}
+			found, inBlockSection = true, true
+			continue
+		case endMarker:
+			inBlockSection = false
+			continue
+		}
+
+		if inBlockSection {
+			actual = append(actual, line)
+		}
+	}
+
+	if !found || inBlockSection {
+		return false, nil
+	}
+	return slices.Equal(actual, hostsRules(urls)), nil
+}
+
+// hostsRules returns the hosts file lines that block the URLs
+func hostsRules(urls []string) []string {
+	var rules []string
+
+	// Expand wildcards to actual hostnames
+	for _, host := range expandWildcards(urls) {
+		// Block both with and without www
+		rules = append(rules, fmt.Sprintf("127.0.0.1 %s", host))
+
+		// Also block IPv6
+		rules = append(rules, fmt.Sprintf("::1 %s", host))
+	}
+
+	return rules
+}
+
+// IsBlocked checks if our blocking rules are currently in place
+func (h *HostsBlocker) IsBlocked() (bool, error) {
+	content, err := os.ReadFile(h.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	return strings.Contains(string(content), beginMarker), nil
+}
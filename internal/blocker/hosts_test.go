@@ -0,0 +1,116 @@
+package blocker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHosts = "127.0.0.1 localhost\n::1 localhost\n"
+
+// newTestHosts returns a blocker for a hosts file in a temporary directory
+func newTestHosts(t *testing.T) *HostsBlocker {
+	t.Helper()
+
+	dir := t.TempDir()
+	h := &HostsBlocker{
+		Path:      filepath.Join(dir, "hosts"),
+		BackupDir: filepath.Join(dir, "backups"),
+	}
+	if err := os.WriteFile(h.Path, []byte(testHosts), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// readHosts returns the content of the hosts file
+func readHosts(t *testing.T, h *HostsBlocker) string {
+	t.Helper()
+	data, err := os.ReadFile(h.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestHostsRoundTrip(t *testing.T) {
+	h := newTestHosts(t)
+	urls := []string{"reddit.com", "twitter.com"}
+
+	if err := h.Block(urls); err != nil {
+		t.Fatalf("Block: %v", err)
+	}
+	content := readHosts(t, h)
+	if !strings.HasPrefix(content, testHosts) {
+		t.Errorf("Block changed existing entries:\n%s", content)
+	}
+	for _, rule := range []string{beginMarker, "127.0.0.1 reddit.com", "::1 twitter.com", endMarker} {
+		if !strings.Contains(content, rule+"\n") {
+			t.Errorf("hosts file lacks %q:\n%s", rule, content)
+		}
+	}
+	if blocked, err := h.IsBlocked(); err != nil || !blocked {
+		t.Errorf("IsBlocked = %v, %v; want true", blocked, err)
+	}
+	if ok, err := h.Verify(urls); err != nil || !ok {
+		t.Errorf("Verify = %v, %v; want true", ok, err)
+	}
+	if ok, _ := h.Verify([]string{"reddit.com"}); ok {
+		t.Error("Verify of other URLs succeeded")
+	}
+
+	// Blocking again replaces the section instead of adding one
+	if err := h.Block(urls); err != nil {
+		t.Fatalf("second Block: %v", err)
+	}
+	if got := readHosts(t, h); got != content {
+		t.Errorf("second Block changed the file:\n%s", got)
+	}
+
+	if err := h.Unblock(); err != nil {
+		t.Fatalf("Unblock: %v", err)
+	}
+	if got := readHosts(t, h); got != testHosts {
+		t.Errorf("Unblock left %q, want %q", got, testHosts)
+	}
+	if blocked, _ := h.IsBlocked(); blocked {
+		t.Error("IsBlocked after Unblock")
+	}
+	if ok, _ := h.Verify(urls); ok {
+		t.Error("Verify after Unblock succeeded")
+	}
+
+	backups, err := h.Backups()
+	if err != nil || len(backups) != 1 {
+		t.Errorf("Backups = %v, %v; want the pristine file once", backups, err)
+	}
+}
+
+func TestHostsVerifyDetectsTampering(t *testing.T) {
+	urls := []string{"reddit.com"}
+
+	tests := []struct {
+		name   string
+		tamper func(string) string
+	}{
+		{"rule removed", func(s string) string { return strings.Replace(s, "127.0.0.1 reddit.com\n", "", 1) }},
+		{"rule commented", func(s string) string { return strings.Replace(s, "127.0.0.1 reddit.com", "# 127.0.0.1 reddit.com", 1) }},
+		{"end marker removed", func(s string) string { return strings.Replace(s, endMarker+"\n", "", 1) }},
+		{"section duplicated", func(s string) string { return s + s[len(testHosts):] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHosts(t)
+			if err := h.Block(urls); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(h.Path, []byte(tt.tamper(readHosts(t, h))), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := h.Verify(urls); err != nil || ok {
+				t.Errorf("Verify = %v, %v; want false", ok, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package blocker
+
+import (
+	"path"
+	"strings"
+)
+
+// pattern is a URL or wildcard pattern split into lowercase DNS labels
+type pattern []string
+
+// compilePatterns normalizes URLs and patterns for matching against hostnames
+func compilePatterns(urls []string) []pattern {
+	var patterns []pattern
+
+	for _, url := range urls {
+		host := normalizeHost(url)
+		if host == "" || host == "*" {
+			continue
+		}
+		patterns = append(patterns, strings.Split(host, "."))
+	}
+
+	return patterns
+}
+
+// normalizeHost strips protocol, path, port and trailing dot from a URL
+func normalizeHost(url string) string {
+	url = strings.TrimSpace(strings.ToLower(url))
+	url = strings.TrimPrefix(url, "http://")
+	url = strings.TrimPrefix(url, "https://")
+
+	if i := strings.IndexAny(url, "/?#"); i >= 0 {
+		url = url[:i]
+	}
+	if i := strings.LastIndex(url, ":"); i >= 0 && !strings.Contains(url[i:], "]") {
+		url = url[:i]
+	}
+
+	return strings.Trim(url, ".")
+}
+
+// matchHost reports whether host matches any of the patterns
+func matchHost(patterns []pattern, host string) bool {
+	labels := strings.Split(normalizeHost(host), ".")
+
+	for _, p := range patterns {
+		if p.match(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the hostname labels match the pattern
+//
+// A pattern without wildcards matches the domain itself and all of its
+// subdomains. A "*" label matches one or more labels, and a leading "*" also
+// matches none, so "*.linkedin.*" matches linkedin.de, www.linkedin.com and
+// static.linkedin.co.uk. A "*" inside a label matches within that label only.
+func (p pattern) match(labels []string) bool {
+	if !strings.Contains(strings.Join(p, "."), "*") {
+		return suffixMatch(p, labels)
+	}
+
+	if p[0] == "*" {
+		return matchLabels(p[1:], labels) || matchLabels(p, labels)
+	}
+	return matchLabels(p, labels)
+}
+
+// suffixMatch reports whether labels end with all labels of p
+func suffixMatch(p pattern, labels []string) bool {
+	if len(labels) < len(p) {
+		return false
+	}
+
+	offset := len(labels) - len(p)
+	for i, label := range p {
+		if labels[offset+i] != label {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLabels matches labels against p, where "*" labels consume one or more labels
+func matchLabels(p pattern, labels []string) bool {
+	if len(p) == 0 {
+		return len(labels) == 0
+	}
+	if len(labels) == 0 {
+		return false
+	}
+
+	if p[0] == "*" {
+		for n := 1; n <= len(labels); n++ {
+			if matchLabels(p[1:], labels[n:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ok, err := path.Match(p[0], labels[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchLabels(p[1:], labels[1:])
+}
@@ -0,0 +1,56 @@
+package blocker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		// Plain domains match themselves and their subdomains
+		{"linkedin.com", "linkedin.com", true},
+		{"linkedin.com", "www.linkedin.com", true},
+		{"linkedin.com", "static.cdn.linkedin.com", true},
+		{"linkedin.com", "notlinkedin.com", false},
+		{"linkedin.com", "linkedin.com.evil.example", false},
+		{"https://LinkedIn.com/feed", "LINKEDIN.com.", true},
+
+		// A "*" label matches one or more labels, a leading one also none
+		{"*.linkedin.*", "linkedin.de", true},
+		{"*.linkedin.*", "www.linkedin.com", true},
+		{"*.linkedin.*", "static.linkedin.co.uk", true},
+		{"*.linkedin.*", "linkedin", false},
+		{"*.linkedin.*", "linkedinx.com", false},
+		{"linkedin.*", "linkedin.co.uk", true},
+		{"linkedin.*", "www.linkedin.com", false},
+		{"*.reddit.com", "reddit.com", true},
+		{"*.reddit.com", "old.reddit.com", true},
+
+		// A "*" inside a label stays within that label
+		{"news*.example.com", "news.example.com", true},
+		{"news*.example.com", "news24.example.com", true},
+		{"news*.example.com", "a.news24.example.com", false},
+		{"news*.example.com", "news.b.example.com", false},
+		{"*cdn.example", "fastcdn.example", true},
+	}
+	for _, tt := range tests {
+		patterns := compilePatterns([]string{tt.pattern})
+		if len(patterns) != 1 {
+			t.Fatalf("compilePatterns(%q) = %v", tt.pattern, patterns)
+		}
+		labels := strings.Split(normalizeHost(tt.host), ".")
+		if got := patterns[0].match(labels); got != tt.want {
+			t.Errorf("%q.match(%q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCompilePatternsSkipsEmpty(t *testing.T) {
+	if got := compilePatterns([]string{"", "*", "  ", "http://"}); len(got) != 0 {
+		t.Errorf("compilePatterns = %v, want none", got)
+	}
+}
@@ -0,0 +1,375 @@
+package blocker
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const nftTable = "selfcontrol"
+
+// Name resolution limits
+const (
+	// resolveWorkers bounds concurrent lookups, so imported lists with many
+	// thousands of hosts resolve quickly without flooding the resolver
+	resolveWorkers = 32
+
+	// resolveTTL is how long Prepare reuses resolved addresses
+	resolveTTL = 10 * time.Minute
+)
+
+// Executor runs the nft command line tool
+type Executor interface {
+	// Run executes nft with args, feeding stdin to it, and returns its output
+	Run(args []string, stdin string) (string, error)
+}
+
+// nftExecutor runs the real nft binary
+type nftExecutor struct{}
+
+func (nftExecutor) Run(args []string, stdin string) (string, error) {
+	cmd := exec.Command("nft", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Run(); err != nil {
+		return out.String(), fmt.Errorf("nft %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(out.String()))
+	}
+	return out.String(), nil
+}
+
+// NftablesBlocker blocks websites by rejecting traffic to their resolved IP
+// addresses in a dedicated nftables table
+// In allowlist mode it rejects all outgoing traffic except to the addresses
+// of the allowed websites.
+type NftablesBlocker struct {
+	// Exec runs nft; replace it to inspect generated rulesets
+	Exec Executor
+
+	// LookupIP resolves a hostname to its addresses
+	LookupIP func(host string) ([]net.IP, error)
+
+	// resolved caches lookups, so Prepare can resolve hosts before the
+	// state is locked and Block finds them there
+	mu       sync.Mutex
+	resolved map[string]resolution
+
+	// applied is the number of addresses in each set of the table applied
+	// last, so Verify notices sets that were flushed or shrunk
+	applied map[string]int
+}
+
+// resolution is a cached lookup; ips is empty for hosts that didn't resolve
+type resolution struct {
+	ips []net.IP
+	at  time.Time
+}
+
+// NewNftablesBlocker returns a blocker that drives the system nft binary
+func NewNftablesBlocker() *NftablesBlocker {
+	return &NftablesBlocker{
+		Exec:     nftExecutor{},
+		LookupIP: net.LookupIP,
+	}
+}
+
+// sharedNftables is returned by New, so lookups cached by Prepare are
+// reused by later calls in the same process
+var sharedNftables = NewNftablesBlocker()
+
+// Prepare resolves the hosts of the URLs, refreshing lookups older than
+// resolveTTL; Block and Allow then reuse them
+func (n *NftablesBlocker) Prepare(urls []string) {
+	n.resolve(expandWildcards(urls), resolveTTL)
+}
+
+// Block resolves the URLs and replaces the selfcontrol table with one that
+// rejects traffic to the resulting addresses
+func (n *NftablesBlocker) Block(urls []string) error {
+	v4, v6 := n.resolve(expandWildcards(urls), 0)
+
+	// Recreating the table in the same transaction replaces any previous rules atomically
+	ruleset := resetTable() + buildRuleset(v4, v6)
+	if _, err := n.Exec.Run([]string{"-f", "-"}, ruleset); err != nil {
+		return fmt.Errorf("failed to apply nftables rules (are you running with sudo?): %w", err)
+	}
+
+	n.setApplied(map[string]int{"blocked4": len(v4), "blocked6": len(v6)})
+	return nil
+}
+
+// Allow resolves the URLs and replaces the selfcontrol table with one that
+// rejects all other outgoing traffic
+func (n *NftablesBlocker) Allow(urls []string) error {
+	v4, v6 := n.resolve(expandWildcards(urls), 0)
+
+	ruleset := resetTable() + buildAllowRuleset(v4, v6)
+	if _, err := n.Exec.Run([]string{"-f", "-"}, ruleset); err != nil {
+		return fmt.Errorf("failed to apply nftables rules (are you running with sudo?): %w", err)
+	}
+
+	n.setApplied(map[string]int{"allowed4": len(v4), "allowed6": len(v6)})
+	return nil
+}
+
+// Unblock removes the selfcontrol table and every rule in it
+func (n *NftablesBlocker) Unblock() error {
+	if _, err := n.Exec.Run([]string{"-f", "-"}, resetTable()); err != nil {
+		return fmt.Errorf("failed to remove nftables rules (are you running with sudo?): %w", err)
+	}
+	n.setApplied(nil)
+	return nil
+}
+
+// setApplied records the set sizes of the table just applied
+func (n *NftablesBlocker) setApplied(sizes map[string]int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.applied = sizes
+}
+
+// IsBlocked checks if the selfcontrol table exists
+func (n *NftablesBlocker) IsBlocked() (bool, error) {
+	out, err := n.Exec.Run([]string{"list", "tables"}, "")
+	if err != nil {
+		return false, fmt.Errorf("failed to list nftables tables: %w", err)
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "table inet "+nftTable {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Verify checks that the selfcontrol table and its reject rules are intact
+// and that the address sets weren't flushed or shrunk
+// The addresses themselves aren't compared since DNS answers change over
+// time.
+func (n *NftablesBlocker) Verify(urls []string) (bool, error) {
+	return n.verifyRules(urls, []string{"blocked4", "blocked6"}, "ip daddr @blocked4 reject", "ip6 daddr @blocked6 reject")
+}
+
+// VerifyAllowed checks that the selfcontrol table and its allowlist rules
+// are intact and that the address sets weren't flushed or shrunk
+func (n *NftablesBlocker) VerifyAllowed(urls []string) (bool, error) {
+	return n.verifyRules(urls, []string{"allowed4", "allowed6"}, "ip daddr @allowed4 accept", "ip6 daddr @allowed6 accept", "policy drop")
+}
+
+// verifyRules checks that the selfcontrol table exists and contains rules,
+// and that its sets hold as many addresses as were applied
+// Without a record of the applied table, such as after a restart, sets that
+// are all empty although there are URLs count as tampered with.
+func (n *NftablesBlocker) verifyRules(urls, sets []string, rules ...string) (bool, error) {
+	blocked, err := n.IsBlocked()
+	if err != nil || !blocked {
+		return false, err
+	}
+
+	out, err := n.Exec.Run([]string{"list", "table", "inet", nftTable}, "")
+	if err != nil {
+		return false, fmt.Errorf("failed to list nftables table: %w", err)
+	}
+
+	for _, rule := range rules {
+		if !strings.Contains(out, rule) {
+			return false, nil
+		}
+	}
+
+	n.mu.Lock()
+	applied := n.applied
+	n.mu.Unlock()
+
+	total := 0
+	for _, set := range sets {
+		count := countElements(out, set)
+		if want, ok := applied[set]; ok && count < want {
+			return false, nil
+		}
+		total += count
+	}
+	if applied == nil && total == 0 && len(urls) > 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// countElements returns the number of elements of a set in the output of
+// nft list table, which may spread them over several lines
+func countElements(table, set string) int {
+	var body strings.Builder
+	inSet := false
+	for _, line := range strings.Split(table, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "set "+set+" {":
+			inSet = true
+		case inSet && line == "}":
+			inSet = false
+		case inSet:
+			body.WriteString(line)
+			body.WriteString(" ")
+		}
+	}
+
+	_, elements, ok := strings.Cut(body.String(), "elements = {")
+	if !ok {
+		return 0
+	}
+	elements, _, _ = strings.Cut(elements, "}")
+
+	count := 0
+	for _, elem := range strings.Split(elements, ",") {
+		if strings.TrimSpace(elem) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// resolve looks up the hosts and splits the unique addresses by family
+// Cached lookups are reused, unless maxAge is set and they are older. Hosts
+// that do not resolve are skipped, since wildcard expansion produces many
+// names that don't exist.
+func (n *NftablesBlocker) resolve(hosts []string, maxAge time.Duration) (v4, v6 []string) {
+	n.lookup(hosts, maxAge)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		for _, ip := range n.resolved[host].ips {
+			addr := ip.String()
+			if seen[addr] {
+				continue
+			}
+			seen[addr] = true
+
+			if ip.To4() != nil {
+				v4 = append(v4, addr)
+			} else {
+				v6 = append(v6, addr)
+			}
+		}
+	}
+
+	sort.Strings(v4)
+	sort.Strings(v6)
+	return v4, v6
+}
+
+// lookup resolves the hosts that aren't cached, or whose lookup is older
+// than maxAge if it is set, with up to resolveWorkers lookups at a time
+func (n *NftablesBlocker) lookup(hosts []string, maxAge time.Duration) {
+	now := time.Now()
+
+	n.mu.Lock()
+	if n.resolved == nil {
+		n.resolved = make(map[string]resolution)
+	}
+	var missing []string
+	queued := make(map[string]bool)
+	for _, host := range hosts {
+		r, ok := n.resolved[host]
+		if (ok && (maxAge == 0 || now.Sub(r.at) < maxAge)) || queued[host] {
+			continue
+		}
+		queued[host] = true
+		missing = append(missing, host)
+	}
+	n.mu.Unlock()
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(resolveWorkers, len(missing)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				ips, _ := n.LookupIP(host)
+
+				n.mu.Lock()
+				n.resolved[host] = resolution{ips: ips, at: now}
+				n.mu.Unlock()
+			}
+		}()
+	}
+	for _, host := range missing {
+		jobs <- host
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// resetTable returns nft commands that delete the selfcontrol table
+// Declaring it first makes the delete succeed even if it doesn't exist yet
+func resetTable() string {
+	return fmt.Sprintf("table inet %s {}\ndelete table inet %s\n", nftTable, nftTable)
+}
+
+// buildRuleset generates the selfcontrol table for the given addresses
+func buildRuleset(v4, v6 []string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "table inet %s {\n", nftTable)
+	writeSet(&b, "blocked4", "ipv4_addr", v4)
+	writeSet(&b, "blocked6", "ipv6_addr", v6)
+
+	b.WriteString("\tchain output {\n")
+	b.WriteString("\t\ttype filter hook output priority 0; policy accept;\n")
+	b.WriteString("\t\tip daddr @blocked4 reject\n")
+	b.WriteString("\t\tip6 daddr @blocked6 reject\n")
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// buildAllowRuleset generates the selfcontrol table allowing only the given
+// addresses
+// Loopback, DNS, DHCP, IPv6 neighbor discovery and replies on connections
+// opened from outside stay allowed, so names can still be resolved and the
+// network keeps working.
+func buildAllowRuleset(v4, v6 []string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "table inet %s {\n", nftTable)
+	writeSet(&b, "allowed4", "ipv4_addr", v4)
+	writeSet(&b, "allowed6", "ipv6_addr", v6)
+
+	b.WriteString("\tchain output {\n")
+	b.WriteString("\t\ttype filter hook output priority 0; policy drop;\n")
+	b.WriteString("\t\toif \"lo\" accept\n")
+	b.WriteString("\t\tct direction reply accept\n")
+	b.WriteString("\t\tudp dport { 53, 67, 547 } accept\n")
+	b.WriteString("\t\ttcp dport 53 accept\n")
+	b.WriteString("\t\ticmpv6 type { nd-router-solicit, nd-neighbor-solicit, nd-neighbor-advert } accept\n")
+	b.WriteString("\t\tip daddr @allowed4 accept\n")
+	b.WriteString("\t\tip6 daddr @allowed6 accept\n")
+	b.WriteString("\t\treject\n")
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// writeSet writes a named address set, omitting the elements if there are none
+func writeSet(b *strings.Builder, name, typ string, addrs []string) {
+	fmt.Fprintf(b, "\tset %s {\n", name)
+	fmt.Fprintf(b, "\t\ttype %s\n", typ)
+	if len(addrs) > 0 {
+		fmt.Fprintf(b, "\t\telements = { %s }\n", strings.Join(addrs, ", "))
+	}
+	b.WriteString("\t}\n")
+}
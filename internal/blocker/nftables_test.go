@@ -0,0 +1,273 @@
+package blocker
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeNft records the rulesets fed to nft and answers list commands
+type fakeNft struct {
+	mu      sync.Mutex
+	applied []string
+
+	// tables and table are the outputs of "list tables" and "list table"
+	tables string
+	table  string
+}
+
+func (f *fakeNft) Run(args []string, stdin string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.Join(args, " ") {
+	case "-f -":
+		f.applied = append(f.applied, stdin)
+		return "", nil
+	case "list tables":
+		return f.tables, nil
+	case "list table inet " + nftTable:
+		return f.table, nil
+	}
+	return "", fmt.Errorf("unexpected nft %s", strings.Join(args, " "))
+}
+
+// last returns the last applied ruleset
+func (f *fakeNft) last(t *testing.T) string {
+	t.Helper()
+	if len(f.applied) == 0 {
+		t.Fatal("no ruleset applied")
+	}
+	return f.applied[len(f.applied)-1]
+}
+
+// fakeLookup resolves hosts from a table and counts the lookups
+type fakeLookup struct {
+	addrs map[string][]string
+	calls atomic.Int32
+}
+
+func (l *fakeLookup) LookupIP(host string) ([]net.IP, error) {
+	l.calls.Add(1)
+	addrs, ok := l.addrs[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	var ips []net.IP
+	for _, a := range addrs {
+		ips = append(ips, net.ParseIP(a))
+	}
+	return ips, nil
+}
+
+func newTestNftables() (*NftablesBlocker, *fakeNft, *fakeLookup) {
+	nft := &fakeNft{}
+	lookup := &fakeLookup{addrs: map[string][]string{
+		"reddit.com":     {"151.101.1.140", "2a04:4e42::396"},
+		"www.reddit.com": {"151.101.1.140"},
+		"twitter.com":    {"104.244.42.1"},
+	}}
+	return &NftablesBlocker{Exec: nft, LookupIP: lookup.LookupIP}, nft, lookup
+}
+
+func TestNftablesBlock(t *testing.T) {
+	n, nft, _ := newTestNftables()
+
+	if err := n.Block([]string{"reddit.com", "www.reddit.com", "twitter.com", "gone.example"}); err != nil {
+		t.Fatalf("Block: %v", err)
+	}
+
+	want := resetTable() + `table inet selfcontrol {
+	set blocked4 {
+		type ipv4_addr
+		elements = { 104.244.42.1, 151.101.1.140 }
+	}
+	set blocked6 {
+		type ipv6_addr
+		elements = { 2a04:4e42::396 }
+	}
+	chain output {
+		type filter hook output priority 0; policy accept;
+		ip daddr @blocked4 reject
+		ip6 daddr @blocked6 reject
+	}
+}
+`
+	if got := nft.last(t); got != want {
+		t.Errorf("ruleset:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNftablesAllow(t *testing.T) {
+	n, nft, _ := newTestNftables()
+
+	if err := n.Allow([]string{"twitter.com"}); err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+
+	got := nft.last(t)
+	for _, want := range []string{
+		"set allowed4 {\n\t\ttype ipv4_addr\n\t\telements = { 104.244.42.1 }\n\t}",
+		"set allowed6 {\n\t\ttype ipv6_addr\n\t}",
+		"policy drop;",
+		"oif \"lo\" accept",
+		"udp dport { 53, 67, 547 } accept",
+		"ip daddr @allowed4 accept",
+		"ip6 daddr @allowed6 accept",
+		"\t\treject\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ruleset lacks %q:\n%s", want, got)
+		}
+	}
+	if !strings.HasPrefix(got, resetTable()) {
+		t.Errorf("ruleset doesn't replace the table first:\n%s", got)
+	}
+}
+
+func TestNftablesUnblock(t *testing.T) {
+	n, nft, _ := newTestNftables()
+
+	if err := n.Unblock(); err != nil {
+		t.Fatalf("Unblock: %v", err)
+	}
+	want := "table inet selfcontrol {}\ndelete table inet selfcontrol\n"
+	if got := nft.last(t); got != want {
+		t.Errorf("ruleset = %q, want %q", got, want)
+	}
+}
+
+func TestNftablesVerify(t *testing.T) {
+	blockTable := buildRuleset([]string{"104.244.42.1"}, nil)
+	allowTable := buildAllowRuleset([]string{"104.244.42.1"}, nil)
+
+	urls := []string{"twitter.com"}
+
+	tests := []struct {
+		name          string
+		tables, table string
+		urls          []string
+		blocked       bool
+		verified      bool
+		allowed       bool
+	}{
+		{"no table", "table inet filter\n", "", urls, false, false, false},
+		{"block rules", "table inet filter\ntable inet selfcontrol\n", blockTable, urls, true, true, false},
+		{"allow rules", "table inet selfcontrol\n", allowTable, urls, true, false, true},
+		{"emptied chain", "table inet selfcontrol\n", "table inet selfcontrol {\n\tchain output {\n\t}\n}\n", urls, true, false, false},
+
+		// Without a record of the applied table, only sets that are all
+		// empty are noticed
+		{"flushed block sets", "table inet selfcontrol\n", buildRuleset(nil, nil), urls, true, false, false},
+		{"flushed allow sets", "table inet selfcontrol\n", buildAllowRuleset(nil, nil), urls, true, false, false},
+		{"empty sets without URLs", "table inet selfcontrol\n", buildRuleset(nil, nil), nil, true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, nft, _ := newTestNftables()
+			nft.tables, nft.table = tt.tables, tt.table
+
+			if got, err := n.IsBlocked(); err != nil || got != tt.blocked {
+				t.Errorf("IsBlocked = %v, %v; want %v", got, err, tt.blocked)
+			}
+			if got, err := n.Verify(tt.urls); err != nil || got != tt.verified {
+				t.Errorf("Verify = %v, %v; want %v", got, err, tt.verified)
+			}
+			if got, err := n.VerifyAllowed(tt.urls); err != nil || got != tt.allowed {
+				t.Errorf("VerifyAllowed = %v, %v; want %v", got, err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestNftablesVerifyShrunkSets(t *testing.T) {
+	urls := []string{"reddit.com", "twitter.com"}
+
+	tests := []struct {
+		name   string
+		apply  func(n *NftablesBlocker) error
+		verify func(n *NftablesBlocker) (bool, error)
+		build  func(v4, v6 []string) string
+	}{
+		{"block", func(n *NftablesBlocker) error { return n.Block(urls) }, func(n *NftablesBlocker) (bool, error) { return n.Verify(urls) }, buildRuleset},
+		{"allow", func(n *NftablesBlocker) error { return n.Allow(urls) }, func(n *NftablesBlocker) (bool, error) { return n.VerifyAllowed(urls) }, buildAllowRuleset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, nft, _ := newTestNftables()
+			if err := tt.apply(n); err != nil {
+				t.Fatal(err)
+			}
+			nft.tables = "table inet selfcontrol\n"
+
+			tables := []struct {
+				name  string
+				table string
+				want  bool
+			}{
+				{"as applied", strings.TrimPrefix(nft.last(t), resetTable()), true},
+				{"one set flushed", tt.build(nil, []string{"2a04:4e42::396"}), false},
+				{"both sets flushed", tt.build(nil, nil), false},
+				{"shrunk set", tt.build([]string{"151.101.1.140"}, []string{"2a04:4e42::396"}), false},
+			}
+			for _, tc := range tables {
+				nft.table = tc.table
+				if got, err := tt.verify(n); err != nil || got != tc.want {
+					t.Errorf("%s: verified = %v, %v; want %v", tc.name, got, err, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCountElements(t *testing.T) {
+	// nft wraps long element lists
+	table := "table inet selfcontrol {\n" +
+		"\tset blocked4 {\n\t\ttype ipv4_addr\n\t\telements = { 104.244.42.1, 151.101.1.140,\n\t\t\t     151.101.65.140 }\n\t}\n" +
+		"\tset blocked6 {\n\t\ttype ipv6_addr\n\t}\n" +
+		"}\n"
+
+	tests := map[string]int{"blocked4": 3, "blocked6": 0, "allowed4": 0}
+	for set, want := range tests {
+		if got := countElements(table, set); got != want {
+			t.Errorf("countElements(%s) = %d, want %d", set, got, want)
+		}
+	}
+}
+
+func TestNftablesPrepareResolvesOnce(t *testing.T) {
+	n, _, lookup := newTestNftables()
+	for i := 0; i < 500; i++ {
+		lookup.addrs[fmt.Sprintf("host%d.example", i)] = []string{fmt.Sprintf("10.0.%d.%d", i/256, i%256)}
+	}
+	var urls []string
+	for host := range lookup.addrs {
+		urls = append(urls, host)
+	}
+
+	hosts := make(map[string]bool)
+	for _, host := range expandWildcards(urls) {
+		hosts[host] = true
+	}
+
+	n.Prepare(urls)
+	if got := int(lookup.calls.Load()); got != len(hosts) {
+		t.Fatalf("Prepare looked up %d hosts, want %d", got, len(hosts))
+	}
+
+	// Block reuses the lookups made by Prepare
+	if err := n.Block(urls); err != nil {
+		t.Fatalf("Block: %v", err)
+	}
+	if got := int(lookup.calls.Load()); got != len(hosts) {
+		t.Errorf("Block looked up %d hosts again", got-len(hosts))
+	}
+	v4, _ := n.resolve(expandWildcards(urls), 0)
+	if len(v4) != 502 {
+		t.Errorf("resolved %d IPv4 addresses, want 502", len(v4))
+	}
+}
@@ -0,0 +1,234 @@
+package blocklist
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// maxLineLength bounds a single line; curated lists have short lines
+const maxLineLength = 1024 * 1024
+
+// Source is a parsed blocklist file
+type Source struct {
+	// Name identifies the source, usually its path
+	Name string
+
+	// Domains are the unique domains and patterns, in file order
+	Domains []string
+
+	// Entries found per syntax
+	Hosts   int
+	Plain   int
+	Adblock int
+	JSON    int
+
+	// Skipped counts lines that aren't comments but couldn't be used, such
+	// as Adblock rules with paths or options
+	Skipped int
+
+	// New and Duplicates are set by Merge: how many domains weren't yet in
+	// the list, and how many were already there or in an earlier source
+	New        int
+	Duplicates int
+}
+
+// hostsIgnored are names hosts files map for the local system, not to
+// block anything
+var hostsIgnored = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+	"0.0.0.0":               true,
+}
+
+// ParseFile parses the blocklist at path, or standard input for "-"
+func ParseFile(path string) (*Source, error) {
+	if path == "-" {
+		return Parse("stdin", os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blocklist: %w", err)
+	}
+	defer f.Close()
+
+	return Parse(path, f)
+}
+
+// Parse reads a blocklist in any of the supported syntaxes, which may be
+// mixed line by line:
+//
+//   - hosts files: "0.0.0.0 example.com", several names per line allowed
+//   - plain lists: one domain or pattern per line
+//   - Adblock Plus domain rules: "||example.com^"
+//
+// Comments ("#" and "!") and Adblock headers are ignored. JSON exports (see
+// Write) are read as a whole, taking the URLs of all their lists.
+func Parse(name string, r io.Reader) (*Source, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	src := &Source{Name: name}
+	seen := make(map[string]bool)
+
+	add := func(domain string) bool {
+		domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+		if !validDomain(domain) || hostsIgnored[domain] {
+			return false
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			src.Domains = append(src.Domains, domain)
+		}
+		return true
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if err := parseJSON(data, src, add); err != nil {
+			return nil, err
+		}
+		return src, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '!' || line[0] == '#' || line[0] == '[' {
+			continue
+		}
+
+		// Adblock Plus: only whole-domain rules without options
+		if rule, ok := strings.CutPrefix(line, "||"); ok {
+			domain, ok := strings.CutSuffix(rule, "^")
+			if ok && add(domain) {
+				src.Adblock++
+			} else {
+				src.Skipped++
+			}
+			continue
+		}
+
+		// Everything else may carry a trailing comment
+		line = stripComment(line)
+		fields := strings.Fields(line)
+
+		switch {
+		case len(fields) == 0:
+			continue
+
+		case net.ParseIP(fields[0]) != nil:
+			// hosts file entry; the address doesn't matter
+			for _, domain := range fields[1:] {
+				if add(domain) {
+					src.Hosts++
+				}
+			}
+
+		case len(fields) == 1 && add(fields[0]):
+			src.Plain++
+
+		default:
+			src.Skipped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return src, nil
+}
+
+// Merge returns the domains of the sources that aren't in existing or an
+// earlier source, in order, and sets each source's New and Duplicates counts
+func Merge(sources []*Source, existing []string) []string {
+	seen := make(map[string]bool, len(existing))
+	for _, url := range existing {
+		seen[strings.ToLower(url)] = true
+	}
+
+	var merged []string
+	for _, src := range sources {
+		src.New, src.Duplicates = 0, 0
+		for _, domain := range src.Domains {
+			if seen[domain] {
+				src.Duplicates++
+				continue
+			}
+			seen[domain] = true
+			merged = append(merged, domain)
+			src.New++
+		}
+	}
+	return merged
+}
+
+// Summary describes the counts of a merged source in one line
+func (s *Source) Summary() string {
+	var kinds []string
+	for _, k := range []struct {
+		n    int
+		name string
+	}{{s.Hosts, "hosts"}, {s.Plain, "plain"}, {s.Adblock, "adblock"}, {s.JSON, "json"}} {
+		if k.n > 0 {
+			kinds = append(kinds, fmt.Sprintf("%d %s", k.n, k.name))
+		}
+	}
+
+	summary := fmt.Sprintf("%d domains", len(s.Domains))
+	if len(kinds) > 0 {
+		summary += " (" + strings.Join(kinds, ", ") + ")"
+	}
+	summary += fmt.Sprintf(", %d new, %d already listed", s.New, s.Duplicates)
+	if s.Skipped > 0 {
+		summary += fmt.Sprintf(", %d lines skipped", s.Skipped)
+	}
+	return summary
+}
+
+// stripComment removes a trailing "#" comment, which has to follow
+// whitespace; a "#" within a field, as in Adblock element hiding rules like
+// "example.com##.ad", doesn't start one
+func stripComment(line string) string {
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			return strings.TrimSpace(line[:i])
+		}
+	}
+	return line
+}
+
+// validDomain reports whether s looks like a domain or wildcard pattern
+func validDomain(s string) bool {
+	if len(s) == 0 || len(s) > 253 || !strings.Contains(s, ".") {
+		return false
+	}
+	if strings.HasPrefix(s, ".") || strings.Contains(s, "..") {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.', c == '_', c == '*':
+		default:
+			return false
+		}
+	}
+	return true
+}
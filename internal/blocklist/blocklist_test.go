@@ -0,0 +1,135 @@
+package blocklist
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		domains []string
+
+		hosts, plain, adblock, skipped int
+	}{
+		{
+			name: "StevenBlack hosts",
+			input: `# Title: StevenBlack/hosts
+127.0.0.1 localhost
+127.0.0.1 localhost.localdomain
+255.255.255.255 broadcasthost
+::1 localhost ip6-localhost ip6-loopback
+0.0.0.0 0.0.0.0
+
+# [Ad servers]
+0.0.0.0 ads.example.com tracker.example.com # two names
+0.0.0.0 Metrics.Example.NET.   #uppercase, trailing dot
+0.0.0.0 ads.example.com
+`,
+			domains: []string{"ads.example.com", "tracker.example.com", "metrics.example.net"},
+			hosts:   4,
+		},
+		{
+			name: "plain list",
+			input: `reddit.com
+*.twitter.com # wildcard pattern
+not a domain
+localhost
+`,
+			domains: []string{"reddit.com", "*.twitter.com"},
+			plain:   2,
+			skipped: 2,
+		},
+		{
+			name: "Adblock rules",
+			input: `[Adblock Plus 2.0]
+! Title: EasyList excerpt
+||ads.example.com^
+||tracker.example.org^$third-party
+||example.net/banner^
+@@||allowed.example.com^
+example.com##.banner
+||cdn.example.com^
+`,
+			domains: []string{"ads.example.com", "cdn.example.com"},
+			adblock: 2,
+			skipped: 4,
+		},
+		{
+			name: "mixed syntaxes",
+			input: `0.0.0.0 reddit.com
+reddit.com
+||reddit.com^
+||news.ycombinator.com^
+`,
+			domains: []string{"reddit.com", "news.ycombinator.com"},
+			hosts:   1,
+			plain:   1,
+			adblock: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := Parse(tt.name, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !slices.Equal(src.Domains, tt.domains) {
+				t.Errorf("domains = %v, want %v", src.Domains, tt.domains)
+			}
+			if src.Hosts != tt.hosts || src.Plain != tt.plain || src.Adblock != tt.adblock || src.Skipped != tt.skipped {
+				t.Errorf("counts = %d hosts, %d plain, %d adblock, %d skipped; want %d, %d, %d, %d",
+					src.Hosts, src.Plain, src.Adblock, src.Skipped, tt.hosts, tt.plain, tt.adblock, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	input := `{
+  "profiles": [
+    {"name": "default", "urls": ["reddit.com", "*.twitter.com"]},
+    {"name": "work", "mode": "allow", "urls": ["reddit.com", "not a domain"]}
+  ]
+}`
+	src, err := Parse("export.json", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want := []string{"reddit.com", "*.twitter.com"}; !slices.Equal(src.Domains, want) {
+		t.Errorf("domains = %v, want %v", src.Domains, want)
+	}
+	if src.JSON != 3 || src.Skipped != 1 {
+		t.Errorf("counts = %d json, %d skipped; want 3, 1", src.JSON, src.Skipped)
+	}
+
+	if _, err := Parse("broken.json", strings.NewReader(`{"profiles": [`)); err == nil {
+		t.Error("invalid JSON was accepted")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first := &Source{Name: "first", Domains: []string{"reddit.com", "youtube.com", "twitter.com"}}
+	second := &Source{Name: "second", Domains: []string{"twitter.com", "facebook.com", "news.ycombinator.com"}}
+	existing := []string{"YouTube.com", "news.ycombinator.com"}
+
+	merged := Merge([]*Source{first, second}, existing)
+
+	if want := []string{"reddit.com", "twitter.com", "facebook.com"}; !slices.Equal(merged, want) {
+		t.Errorf("merged = %v, want %v", merged, want)
+	}
+	if first.New != 2 || first.Duplicates != 1 {
+		t.Errorf("first: %d new, %d duplicates; want 2, 1", first.New, first.Duplicates)
+	}
+	if second.New != 1 || second.Duplicates != 2 {
+		t.Errorf("second: %d new, %d duplicates; want 1, 2", second.New, second.Duplicates)
+	}
+
+	// Merging again recounts instead of adding up
+	Merge([]*Source{first, second}, existing)
+	if first.New != 2 || second.Duplicates != 2 {
+		t.Errorf("counts after merging again: first %d new, second %d duplicates", first.New, second.Duplicates)
+	}
+}
@@ -0,0 +1,121 @@
+package blocklist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/phil/selfcontrol/internal/fsutil"
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// Export formats
+const (
+	FormatText  = "txt"
+	FormatJSON  = "json"
+	FormatHosts = "hosts"
+)
+
+// Formats lists the export formats
+var Formats = []string{FormatText, FormatJSON, FormatHosts}
+
+// List is a named list of URLs or patterns, such as an exported profile
+type List struct {
+	Name string   `json:"name"`
+	Mode string   `json:"mode,omitempty"`
+	URLs []string `json:"urls"`
+}
+
+// Lists converts profiles for export
+func Lists(profiles []*state.Profile) []List {
+	lists := make([]List, 0, len(profiles))
+	for _, p := range profiles {
+		lists = append(lists, List{Name: p.Name, Mode: p.Mode, URLs: p.URLs})
+	}
+	return lists
+}
+
+// Export is the layout of JSON exports
+type Export struct {
+	Profiles []List `json:"profiles"`
+}
+
+// Write writes the lists in the given format
+// All formats can be imported again; hosts files can't express wildcard
+// patterns, which are written as comments there. Allowlists are refused in
+// hosts files, which would block exactly the sites they allow.
+func Write(w io.Writer, format string, lists []List) error {
+	if format == FormatHosts {
+		for _, list := range lists {
+			if list.Mode == state.ModeAllow {
+				return fmt.Errorf("profile %q is an allowlist, which a hosts file would block; export it as %s or %s", list.Name, FormatText, FormatJSON)
+			}
+		}
+	}
+
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(Export{Profiles: lists})
+
+	case FormatText, FormatHosts:
+		var b strings.Builder
+		for i, list := range lists {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "# selfcontrol profile: %s\n", list.Name)
+			if list.Mode != "" {
+				fmt.Fprintf(&b, "# mode: %s\n", list.Mode)
+			}
+			for _, url := range list.URLs {
+				switch {
+				case format == FormatText:
+					b.WriteString(url + "\n")
+				case strings.Contains(url, "*"):
+					fmt.Fprintf(&b, "# %s (pattern, not supported in hosts files)\n", url)
+				default:
+					fmt.Fprintf(&b, "0.0.0.0 %s\n", url)
+				}
+			}
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+
+	default:
+		return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// WriteFile writes the lists to a file, replacing it atomically
+func WriteFile(path, format string, lists []List) error {
+	var b bytes.Buffer
+	if err := Write(&b, format, lists); err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(path, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseJSON adds the URLs of all lists in a JSON export to src
+func parseJSON(data []byte, src *Source, add func(string) bool) error {
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", src.Name, err)
+	}
+	for _, list := range export.Profiles {
+		for _, url := range list.URLs {
+			if add(url) {
+				src.JSON++
+			} else {
+				src.Skipped++
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package blocklist
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	lists := []List{
+		{Name: "default", URLs: []string{"reddit.com", "*.twitter.com", "youtube.com"}},
+		{Name: "news", URLs: []string{"news.ycombinator.com", "reddit.com"}},
+	}
+
+	tests := []struct {
+		format  string
+		domains []string
+	}{
+		{FormatText, []string{"reddit.com", "*.twitter.com", "youtube.com", "news.ycombinator.com"}},
+		{FormatJSON, []string{"reddit.com", "*.twitter.com", "youtube.com", "news.ycombinator.com"}},
+
+		// Patterns are only kept as comments in hosts files
+		{FormatHosts, []string{"reddit.com", "youtube.com", "news.ycombinator.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Write(&b, tt.format, lists); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			src, err := Parse("export."+tt.format, &b)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !slices.Equal(src.Domains, tt.domains) {
+				t.Errorf("domains = %v, want %v", src.Domains, tt.domains)
+			}
+			if src.Skipped != 0 {
+				t.Errorf("%d lines skipped", src.Skipped)
+			}
+		})
+	}
+}
+
+func TestWriteRefusesAllowlistHosts(t *testing.T) {
+	lists := []List{
+		{Name: "default", URLs: []string{"reddit.com"}},
+		{Name: "work", Mode: state.ModeAllow, URLs: []string{"github.com"}},
+	}
+
+	var b bytes.Buffer
+	err := Write(&b, FormatHosts, lists)
+	if err == nil || !strings.Contains(err.Error(), `"work"`) {
+		t.Fatalf("Write = %v, want an error naming the allowlist", err)
+	}
+	if b.Len() > 0 {
+		t.Errorf("partial hosts file written: %q", b.String())
+	}
+
+	// The other formats keep the mode
+	for _, format := range []string{FormatText, FormatJSON} {
+		b.Reset()
+		if err := Write(&b, format, lists); err != nil {
+			t.Errorf("Write(%s): %v", format, err)
+		}
+		if !strings.Contains(b.String(), state.ModeAllow) {
+			t.Errorf("%s export lost the mode: %s", format, b.String())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package clock
+
+import "time"
+
+// Reading pairs the wall clock with the time since boot, which can't be
+// changed by setting the system clock
+type Reading struct {
+	Wall   time.Time     `json:"wall"`
+	Uptime time.Duration `json:"uptime"`
+
+	// BootID identifies the boot Uptime counts from; it is empty if the
+	// time since boot isn't available on this platform
+	BootID string `json:"boot_id,omitempty"`
+}
+
+// Read returns the current clocks
+func Read() Reading {
+	r := Reading{Wall: time.Now()}
+
+	uptime, err := sinceBoot()
+	if err != nil {
+		return r
+	}
+	id, err := bootID()
+	if err != nil {
+		return r
+	}
+
+	r.Uptime, r.BootID = uptime, id
+	return r
+}
+
+// Elapsed returns the wall-clock and boot-clock time between two readings
+// ok is false if they weren't taken during the same boot.
+func Elapsed(from, to Reading) (wall, boot time.Duration, ok bool) {
+	if from.BootID == "" || from.BootID != to.BootID {
+		return 0, 0, false
+	}
+	return to.Wall.Sub(from.Wall), to.Uptime - from.Uptime, true
+}
@@ -0,0 +1,37 @@
+//go:build darwin
+
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// sinceBoot reads CLOCK_MONOTONIC, which on macOS keeps counting during sleep
+func sinceBoot() (time.Duration, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0, err
+	}
+	return time.Duration(ts.Nano()), nil
+}
+
+var (
+	bootIDOnce sync.Once
+	bootIDVal  string
+	bootIDErr  error
+)
+
+// bootID returns the kernel's UUID for the current boot session
+func bootID() (string, error) {
+	bootIDOnce.Do(func() {
+		bootIDVal, bootIDErr = unix.Sysctl("kern.bootsessionuuid")
+		if bootIDErr == nil && bootIDVal == "" {
+			bootIDErr = fmt.Errorf("empty boot session UUID")
+		}
+	})
+	return bootIDVal, bootIDErr
+}
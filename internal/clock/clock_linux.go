@@ -0,0 +1,36 @@
+//go:build linux
+
+package clock
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// sinceBoot reads CLOCK_BOOTTIME, which keeps counting during suspend
+func sinceBoot() (time.Duration, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts); err != nil {
+		return 0, err
+	}
+	return time.Duration(ts.Nano()), nil
+}
+
+var (
+	bootIDOnce sync.Once
+	bootIDVal  string
+	bootIDErr  error
+)
+
+// bootID returns the kernel's random ID for the current boot
+func bootID() (string, error) {
+	bootIDOnce.Do(func() {
+		data, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+		bootIDVal, bootIDErr = strings.TrimSpace(string(data)), err
+	})
+	return bootIDVal, bootIDErr
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package clock
+
+import (
+	"errors"
+	"time"
+)
+
+var errUnsupported = errors.New("boot clock not supported on this platform")
+
+func sinceBoot() (time.Duration, error) {
+	return 0, errUnsupported
+}
+
+func bootID() (string, error) {
+	return "", errUnsupported
+}
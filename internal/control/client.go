@@ -0,0 +1,186 @@
+package control
+
+import (
+	"errors"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// Client talks to the daemon over its control socket
+type Client struct {
+	path string
+	rpc  *rpc.Client
+}
+
+// Dial connects to the control socket at path
+func Dial(path string) (*Client, error) {
+	c := &Client{path: path}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// connect (re)establishes the connection to the daemon
+func (c *Client) connect() error {
+	rc, err := jsonrpc.Dial("unix", c.path)
+	if err != nil {
+		return err
+	}
+	c.rpc = rc
+	return nil
+}
+
+// knownErrors are errors callers check for; net/rpc only transmits the
+// message, so they are restored from it
+var knownErrors = []error{errPermission, state.ErrSessionLocked, state.ErrAllowlistLocked, state.ErrWrongChallenge, blocker.ErrAllowUnsupported}
+
+// call invokes a method, reconnecting once if the daemon was restarted
+func (c *Client) call(method string, args, reply any) error {
+	err := c.rpc.Call(rpcName+"."+method, args, reply)
+	if errors.Is(err, rpc.ErrShutdown) {
+		if err := c.connect(); err != nil {
+			return err
+		}
+		err = c.rpc.Call(rpcName+"."+method, args, reply)
+	}
+
+	var serverErr rpc.ServerError
+	if errors.As(err, &serverErr) {
+		for _, known := range knownErrors {
+			if string(serverErr) == known.Error() {
+				return known
+			}
+		}
+	}
+	return err
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+func (c *Client) Status() (*state.AppState, error) {
+	var st state.AppState
+	if err := c.call("Status", &Empty{}, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (c *Client) AddURL(url string) error {
+	return c.call("AddURL", &URLArgs{URLs: []string{url}}, &Empty{})
+}
+
+func (c *Client) AddURLs(urls []string) error {
+	return c.call("AddURLs", &URLArgs{URLs: urls}, &Empty{})
+}
+
+func (c *Client) RemoveURLs(urls []string) error {
+	return c.call("RemoveURLs", &URLArgs{URLs: urls}, &Empty{})
+}
+
+func (c *Client) StartSession(duration time.Duration, label string, profiles []string) error {
+	return c.call("StartSession", &StartArgs{Duration: duration, Label: label, Profiles: profiles}, &Empty{})
+}
+
+func (c *Client) StartSessionUntil(end time.Time, label string, profiles []string) error {
+	args := &StartArgs{End: end, Label: label, Profiles: profiles}
+	if loc := end.Location(); loc != time.Local {
+		args.Zone = loc.String()
+	}
+	return c.call("StartSessionUntil", args, &Empty{})
+}
+
+func (c *Client) AddProfile(name string) error {
+	return c.call("AddProfile", &ProfileArgs{Name: name}, &Empty{})
+}
+
+func (c *Client) RemoveProfile(name string) error {
+	return c.call("RemoveProfile", &ProfileArgs{Name: name}, &Empty{})
+}
+
+func (c *Client) SelectProfile(name string) error {
+	return c.call("SelectProfile", &ProfileArgs{Name: name}, &Empty{})
+}
+
+func (c *Client) SetDefaultDuration(profile string, duration time.Duration) error {
+	return c.call("SetDefaultDuration", &ProfileArgs{Name: profile, Duration: duration}, &Empty{})
+}
+
+func (c *Client) SetProfileMode(profile, mode string) error {
+	return c.call("SetProfileMode", &ProfileArgs{Name: profile, Mode: mode}, &Empty{})
+}
+
+func (c *Client) Subscribe(profile, url string, refresh time.Duration) error {
+	return c.call("Subscribe", &SubscribeArgs{Profile: profile, URL: url, Refresh: refresh}, &Empty{})
+}
+
+func (c *Client) Unsubscribe(profile, url string) error {
+	return c.call("Unsubscribe", &SubscribeArgs{Profile: profile, URL: url}, &Empty{})
+}
+
+func (c *Client) Subscriptions() ([]state.SubscriptionStatus, error) {
+	var subs []state.SubscriptionStatus
+	if err := c.call("Subscriptions", &Empty{}, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (c *Client) History() ([]state.Event, error) {
+	var events []state.Event
+	if err := c.call("History", &Empty{}, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (c *Client) Blocked() (bool, error) {
+	var blocked bool
+	if err := c.call("Blocked", &Empty{}, &blocked); err != nil {
+		return false, err
+	}
+	return blocked, nil
+}
+
+func (c *Client) RequestUnlock() (string, error) {
+	var challenge string
+	if err := c.call("RequestUnlock", &Empty{}, &challenge); err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+func (c *Client) ConfirmUnlock(typed string) (time.Time, error) {
+	var at time.Time
+	if err := c.call("ConfirmUnlock", &UnlockArgs{Typed: typed}, &at); err != nil {
+		return time.Time{}, err
+	}
+	return at, nil
+}
+
+func (c *Client) CancelUnlock() error {
+	return c.call("CancelUnlock", &Empty{}, &Empty{})
+}
+
+func (c *Client) ImportLegacy(data []byte) (bool, error) {
+	var imported bool
+	if err := c.call("ImportLegacy", &ImportArgs{Data: data}, &imported); err != nil {
+		return false, err
+	}
+	return imported, nil
+}
+
+func (c *Client) Sessions() ([]state.HistoryEntry, error) {
+	var sessions []state.HistoryEntry
+	if err := c.call("Sessions", &Empty{}, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,119 @@
+package control
+
+import (
+	"os"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// SocketPath is the Unix socket the daemon serves the control API on
+const SocketPath = "/var/run/selfcontrol.sock"
+
+// Service is the set of operations the TUI and CLI perform on the blocker
+//
+// The daemon serves it over SocketPath so clients can run unprivileged;
+// Local implements it directly for when the daemon isn't running.
+type Service interface {
+	// Status returns the current state, ending an expired session first
+	Status() (*state.AppState, error)
+
+	// AddURL adds a URL or pattern to the current profile
+	AddURL(url string) error
+
+	// AddURLs adds many URLs or patterns to the current profile at once,
+	// such as an imported blocklist
+	AddURLs(urls []string) error
+
+	// RemoveURLs removes URLs or patterns from the current profile
+	RemoveURLs(urls []string) error
+
+	// StartSession starts blocking the given profiles, or the current
+	// profile if none are given, for the given duration
+	StartSession(duration time.Duration, label string, profiles []string) error
+
+	// StartSessionUntil is like StartSession with an absolute end time; the
+	// time zone of end is recorded in the session
+	StartSessionUntil(end time.Time, label string, profiles []string) error
+
+	// AddProfile creates an empty profile
+	AddProfile(name string) error
+
+	// RemoveProfile deletes a profile
+	RemoveProfile(name string) error
+
+	// SelectProfile makes a profile the current one
+	SelectProfile(name string) error
+
+	// SetDefaultDuration sets the duration preselected for a profile
+	SetDefaultDuration(profile string, duration time.Duration) error
+
+	// SetProfileMode makes a profile a blocklist (state.ModeBlock) or an
+	// allowlist (state.ModeAllow)
+	SetProfileMode(profile, mode string) error
+
+	// Subscribe makes a profile follow a remote blocklist, fetched by the
+	// daemon every refresh (state.DefaultRefresh if zero)
+	Subscribe(profile, url string, refresh time.Duration) error
+
+	// Unsubscribe stops a profile from following a remote blocklist
+	Unsubscribe(profile, url string) error
+
+	// Subscriptions returns the subscriptions of all profiles with the
+	// state of their cached copies
+	Subscriptions() ([]state.SubscriptionStatus, error)
+
+	// History returns the recorded events, oldest first
+	History() ([]state.Event, error)
+
+	// Sessions returns the session history, oldest first
+	Sessions() ([]state.HistoryEntry, error)
+
+	// Blocked reports whether the backend's blocking rules are in place
+	Blocked() (bool, error)
+
+	// RequestUnlock starts an emergency unlock and returns the challenge
+	// the user has to type
+	RequestUnlock() (string, error)
+
+	// ConfirmUnlock checks the typed challenge and returns when the
+	// session will be unlocked
+	ConfirmUnlock(typed string) (time.Time, error)
+
+	// CancelUnlock withdraws an emergency unlock
+	CancelUnlock() error
+
+	// ImportLegacy makes the contents of a legacy state file the state if
+	// there is none yet, returning whether it did
+	ImportLegacy(data []byte) (bool, error)
+}
+
+// Connect returns a client for the daemon if it is running, and otherwise a
+// Local service that needs root to change the blocking rules
+func Connect() Service {
+	var svc Service
+	if client, err := Dial(SocketPath); err == nil {
+		svc = client
+	} else {
+		svc = NewLocal()
+	}
+	importLegacy(svc)
+	return svc
+}
+
+// importLegacy moves the state file of earlier versions, which lives in the
+// user's home directory, into the service
+// Failures leave the file in place to be tried again next time.
+func importLegacy(svc Service) {
+	path := state.LegacyStatePath()
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if imported, err := svc.ImportLegacy(data); err == nil && imported {
+		os.Rename(path, path+".migrated")
+	}
+}
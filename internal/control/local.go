@@ -0,0 +1,353 @@
+package control
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// Local performs operations directly on the state file and blocker
+// It is used by the daemon to serve requests, and by clients as a fallback
+type Local struct{}
+
+// NewLocal returns a service operating on the local state file
+func NewLocal() *Local {
+	return &Local{}
+}
+
+// Status returns the current state, unblocking an expired session first
+func (l *Local) Status() (*state.AppState, error) {
+	st, err := state.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if _, over := st.SessionOver(); !over {
+		return st, nil
+	}
+
+	// Check if session expired or was unlocked and clean up
+	err = state.Update(func(st *state.AppState) error {
+		reason, over := st.SessionOver()
+		if !over {
+			return nil
+		}
+
+		b, err := blocker.New(st.Backend)
+		if err != nil {
+			return err
+		}
+
+		// Session expired, unblock
+		if err := b.Unblock(); err != nil {
+			return fmt.Errorf("session expired but failed to unblock: %w", err)
+		}
+
+		if reason == state.EndCancelled {
+			st.RecordEvent(state.EventUnlock, "session ended by emergency unlock")
+		}
+		st.EndSession(reason)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state.Load()
+}
+
+// Prepare runs change on a copy of the state and, if it changes the URLs
+// the active session enforces, lets the backend resolve them ahead of time
+// Resolving thousands of imported or subscribed hosts takes a while, which
+// would otherwise be spent holding the state lock. Errors are left to the
+// real change.
+func Prepare(change func(st *state.AppState) error) {
+	st, err := state.Load()
+	if err != nil {
+		return
+	}
+	before := len(st.SessionURLs())
+	if err := change(st); err != nil || !st.IsSessionActive() || len(st.SessionURLs()) == before {
+		return
+	}
+
+	b, err := blocker.New(st.Backend)
+	if err != nil {
+		return
+	}
+	blocker.Prepare(b, st.SessionURLs())
+}
+
+// AddURL adds a URL or pattern to the current profile, applying it right
+// away if the active session enforces the profile
+func (l *Local) AddURL(url string) error {
+	Prepare(func(st *state.AppState) error { return st.AddURL(url) })
+
+	return state.Update(func(st *state.AppState) error {
+		if err := st.AddURL(url); err != nil {
+			return err
+		}
+		if !st.IsLocked(st.CurrentProfile) {
+			return nil
+		}
+		return applySession(st)
+	})
+}
+
+// AddURLs adds URLs or patterns to the current profile in one update,
+// applying them right away if the active session enforces the profile
+func (l *Local) AddURLs(urls []string) error {
+	Prepare(func(st *state.AppState) error { return st.AddURLs(urls) })
+
+	return state.Update(func(st *state.AppState) error {
+		if err := st.AddURLs(urls); err != nil {
+			return err
+		}
+		if !st.IsLocked(st.CurrentProfile) {
+			return nil
+		}
+		return applySession(st)
+	})
+}
+
+// applySession re-applies the active session's rules after its URLs changed
+func applySession(st *state.AppState) error {
+	b, err := blocker.New(st.Backend)
+	if err != nil {
+		return err
+	}
+	if err := blocker.Apply(b, st.SessionURLs(), st.ActiveSession.Allowlist()); err != nil {
+		return fmt.Errorf("failed to apply blocking: %w", err)
+	}
+	return nil
+}
+
+// RemoveURLs removes URLs or patterns from the current profile, applying it
+// right away if an allowlist session enforces the profile
+func (l *Local) RemoveURLs(urls []string) error {
+	remove := make(map[string]bool)
+	for _, url := range urls {
+		remove[url] = true
+	}
+
+	return state.Update(func(st *state.AppState) error {
+		var indices []int
+		for i, url := range st.Current().URLs {
+			if remove[url] {
+				indices = append(indices, i)
+			}
+		}
+
+		if err := st.RemoveURLs(indices); err != nil {
+			return err
+		}
+		if !st.IsLocked(st.CurrentProfile) {
+			return nil
+		}
+		return applySession(st)
+	})
+}
+
+// StartSession starts a session and applies the blocking rules
+func (l *Local) StartSession(duration time.Duration, label string, profiles []string) error {
+	return l.StartSessionUntil(time.Now().Add(duration), label, profiles)
+}
+
+// StartSessionUntil starts a session ending at end and applies the blocking
+// rules
+func (l *Local) StartSessionUntil(end time.Time, label string, profiles []string) error {
+	if !end.After(time.Now()) {
+		return fmt.Errorf("end time %s is in the past", end.Format("2006-01-02 15:04"))
+	}
+
+	Prepare(func(st *state.AppState) error {
+		if st.IsSessionActive() {
+			return fmt.Errorf("a session is already active")
+		}
+		st.StartSessionUntil(end, label, profiles)
+		return nil
+	})
+
+	return state.Update(func(st *state.AppState) error {
+		if st.IsSessionActive() {
+			return fmt.Errorf("a session is already active")
+		}
+		for _, name := range profiles {
+			if st.Profile(name) == nil {
+				return fmt.Errorf("no profile named %q", name)
+			}
+		}
+		names := profiles
+		if len(names) == 0 {
+			names = []string{st.CurrentProfile}
+		}
+		mode, err := st.ProfilesMode(names)
+		if err != nil {
+			return err
+		}
+
+		b, err := blocker.New(st.Backend)
+		if err != nil {
+			return err
+		}
+		if mode == state.ModeAllow && !blocker.CanAllow(b, st.DNS != nil && st.DNS.Enabled) {
+			return blocker.ErrAllowUnsupported
+		}
+
+		st.StartSessionUntil(end, label, profiles)
+		if mode == state.ModeBlock && len(st.SessionURLs()) == 0 {
+			if len(st.ActiveSession.Subscriptions) > 0 {
+				return fmt.Errorf("no URLs to block yet: the subscribed lists haven't been fetched")
+			}
+			return fmt.Errorf("no URLs to block")
+		}
+
+		// Apply blocking
+		if err := blocker.Apply(b, st.SessionURLs(), st.ActiveSession.Allowlist()); err != nil {
+			return fmt.Errorf("failed to apply blocking: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddProfile creates an empty profile
+func (l *Local) AddProfile(name string) error {
+	return state.Update(func(st *state.AppState) error {
+		return st.AddProfile(name)
+	})
+}
+
+// RemoveProfile deletes a profile
+func (l *Local) RemoveProfile(name string) error {
+	return state.Update(func(st *state.AppState) error {
+		return st.RemoveProfile(name)
+	})
+}
+
+// SelectProfile makes a profile the current one
+func (l *Local) SelectProfile(name string) error {
+	return state.Update(func(st *state.AppState) error {
+		return st.SelectProfile(name)
+	})
+}
+
+// SetDefaultDuration sets the duration preselected for a profile
+func (l *Local) SetDefaultDuration(profile string, duration time.Duration) error {
+	return state.Update(func(st *state.AppState) error {
+		p := st.Profile(profile)
+		if p == nil {
+			return fmt.Errorf("no profile named %q", profile)
+		}
+		p.DefaultDuration = duration.String()
+		return nil
+	})
+}
+
+// SetProfileMode switches a profile between blocklist and allowlist
+func (l *Local) SetProfileMode(profile, mode string) error {
+	return state.Update(func(st *state.AppState) error {
+		return st.SetProfileMode(profile, mode)
+	})
+}
+
+// Subscribe makes a profile follow a remote blocklist, applying its cached
+// domains right away if the active session enforces the profile
+func (l *Local) Subscribe(profile, url string, refresh time.Duration) error {
+	Prepare(func(st *state.AppState) error { return st.Subscribe(profile, url, refresh) })
+
+	return state.Update(func(st *state.AppState) error {
+		if err := st.Subscribe(profile, url, refresh); err != nil {
+			return err
+		}
+		if !st.IsLocked(profile) {
+			return nil
+		}
+		return applySession(st)
+	})
+}
+
+// Unsubscribe stops a profile from following a remote blocklist
+func (l *Local) Unsubscribe(profile, url string) error {
+	return state.Update(func(st *state.AppState) error {
+		return st.Unsubscribe(profile, url)
+	})
+}
+
+// Subscriptions returns the subscriptions of all profiles
+func (l *Local) Subscriptions() ([]state.SubscriptionStatus, error) {
+	st, err := state.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return st.SubscriptionStatuses()
+}
+
+// History returns the recorded events
+func (l *Local) History() ([]state.Event, error) {
+	st, err := state.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return st.Events, nil
+}
+
+// Blocked reports whether the configured backend is blocking
+func (l *Local) Blocked() (bool, error) {
+	st, err := state.Load()
+	if err != nil {
+		return false, fmt.Errorf("failed to load state: %w", err)
+	}
+	b, err := blocker.New(st.Backend)
+	if err != nil {
+		return false, err
+	}
+	if st.IsSessionActive() && st.ActiveSession.Allowlist() {
+		// Backends that can't allow leave the session to the DNS sinkhole
+		return blocker.Check(b, st.SessionURLs(), true)
+	}
+	return b.IsBlocked()
+}
+
+// RequestUnlock starts an emergency unlock of the active session
+func (l *Local) RequestUnlock() (string, error) {
+	var challenge string
+	err := state.Update(func(st *state.AppState) error {
+		var err error
+		challenge, err = st.RequestUnlock()
+		return err
+	})
+	return challenge, err
+}
+
+// ConfirmUnlock schedules the emergency unlock if the challenge matches
+func (l *Local) ConfirmUnlock(typed string) (time.Time, error) {
+	var at time.Time
+	err := state.Update(func(st *state.AppState) error {
+		var err error
+		at, err = st.ConfirmUnlock(typed)
+		return err
+	})
+	return at, err
+}
+
+// CancelUnlock withdraws an emergency unlock
+func (l *Local) CancelUnlock() error {
+	return state.Update(func(st *state.AppState) error {
+		return st.CancelUnlock()
+	})
+}
+
+// ImportLegacy makes a legacy state file the state if there is none yet
+func (l *Local) ImportLegacy(data []byte) (bool, error) {
+	return state.ImportLegacy(data)
+}
+
+// Sessions returns the session history
+func (l *Local) Sessions() ([]state.HistoryEntry, error) {
+	st, err := state.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return state.LoadHistory(st.ActiveSession)
+}
@@ -0,0 +1,321 @@
+package control
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// rpcName is the name the API is registered under
+const rpcName = "SelfControl"
+
+// errPermission is returned to peers that may only read
+var errPermission = errors.New("permission denied: run as root or join the selfcontrol group")
+
+// IsPermission reports whether err means the caller isn't allowed to make
+// the change or reach the daemon, as opposed to the change failing
+func IsPermission(err error) bool {
+	return errors.Is(err, errPermission) || errors.Is(err, fs.ErrPermission)
+}
+
+// Empty is used for requests and replies without data
+type Empty struct{}
+
+// URLArgs carries URLs for AddURL, AddURLs and RemoveURLs
+type URLArgs struct {
+	URLs []string
+}
+
+// StartArgs carries the parameters of StartSession and StartSessionUntil
+type StartArgs struct {
+	Duration time.Duration
+	Label    string
+	Profiles []string
+
+	// End and Zone are used by StartSessionUntil; Zone is the IANA name of
+	// End's location, which JSON doesn't preserve
+	End  time.Time
+	Zone string
+}
+
+// UnlockArgs carries the typed challenge of ConfirmUnlock
+type UnlockArgs struct {
+	Typed string
+}
+
+// ImportArgs carries the legacy state file of ImportLegacy
+type ImportArgs struct {
+	Data []byte
+}
+
+// ProfileArgs carries the parameters of the profile methods
+type ProfileArgs struct {
+	Name     string
+	Duration time.Duration
+	Mode     string
+}
+
+// SubscribeArgs carries the parameters of Subscribe and Unsubscribe
+type SubscribeArgs struct {
+	Profile string
+	URL     string
+	Refresh time.Duration
+}
+
+// api exposes a Service over net/rpc for one connection
+type api struct {
+	svc Service
+
+	// mu is shared by all connections to serialize changes; reads only
+	// take the shared state lock, so they aren't held up by a change that
+	// is resolving names
+	mu *sync.Mutex
+
+	// privileged is set for peers allowed to change the state
+	privileged bool
+}
+
+func (a *api) Status(_ *Empty, reply *state.AppState) error {
+	st, err := a.svc.Status()
+	if err != nil {
+		return err
+	}
+	if !a.privileged && st.ActiveSession != nil {
+		// Peers that can't unlock have no business with the challenge
+		session := *st.ActiveSession
+		session.Unlock = nil
+		st.ActiveSession = &session
+	}
+	*reply = *st
+	return nil
+}
+
+func (a *api) AddURL(args *URLArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	for _, url := range args.URLs {
+		if err := a.svc.AddURL(url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *api) AddURLs(args *URLArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.AddURLs(args.URLs)
+}
+
+func (a *api) RemoveURLs(args *URLArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.RemoveURLs(args.URLs)
+}
+
+func (a *api) StartSession(args *StartArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.StartSession(args.Duration, args.Label, args.Profiles)
+}
+
+func (a *api) StartSessionUntil(args *StartArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+
+	end := args.End
+	if args.Zone != "" {
+		loc, err := time.LoadLocation(args.Zone)
+		if err != nil {
+			return err
+		}
+		end = end.In(loc)
+	} else {
+		end = end.Local()
+	}
+	return a.svc.StartSessionUntil(end, args.Label, args.Profiles)
+}
+
+func (a *api) AddProfile(args *ProfileArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.AddProfile(args.Name)
+}
+
+func (a *api) RemoveProfile(args *ProfileArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.RemoveProfile(args.Name)
+}
+
+func (a *api) SelectProfile(args *ProfileArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.SelectProfile(args.Name)
+}
+
+func (a *api) SetDefaultDuration(args *ProfileArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.SetDefaultDuration(args.Name, args.Duration)
+}
+
+func (a *api) SetProfileMode(args *ProfileArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.SetProfileMode(args.Name, args.Mode)
+}
+
+func (a *api) Subscribe(args *SubscribeArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.Subscribe(args.Profile, args.URL, args.Refresh)
+}
+
+func (a *api) Unsubscribe(args *SubscribeArgs, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.Unsubscribe(args.Profile, args.URL)
+}
+
+func (a *api) Subscriptions(_ *Empty, reply *[]state.SubscriptionStatus) error {
+	subs, err := a.svc.Subscriptions()
+	if err != nil {
+		return err
+	}
+	*reply = subs
+	return nil
+}
+
+func (a *api) History(_ *Empty, reply *[]state.Event) error {
+	events, err := a.svc.History()
+	if err != nil {
+		return err
+	}
+	*reply = events
+	return nil
+}
+
+func (a *api) Blocked(_ *Empty, reply *bool) error {
+	blocked, err := a.svc.Blocked()
+	if err != nil {
+		return err
+	}
+	*reply = blocked
+	return nil
+}
+
+func (a *api) RequestUnlock(_ *Empty, reply *string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	challenge, err := a.svc.RequestUnlock()
+	if err != nil {
+		return err
+	}
+	*reply = challenge
+	return nil
+}
+
+func (a *api) ConfirmUnlock(args *UnlockArgs, reply *time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	at, err := a.svc.ConfirmUnlock(args.Typed)
+	if err != nil {
+		return err
+	}
+	*reply = at
+	return nil
+}
+
+func (a *api) CancelUnlock(_ *Empty, _ *Empty) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	return a.svc.CancelUnlock()
+}
+
+func (a *api) Sessions(_ *Empty, reply *[]state.HistoryEntry) error {
+	sessions, err := a.svc.Sessions()
+	if err != nil {
+		return err
+	}
+	*reply = sessions
+	return nil
+}
+
+func (a *api) ImportLegacy(args *ImportArgs, reply *bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.privileged {
+		return errPermission
+	}
+	imported, err := a.svc.ImportLegacy(args.Data)
+	if err != nil {
+		return err
+	}
+	*reply = imported
+	return nil
+}
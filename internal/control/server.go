@@ -0,0 +1,112 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// controlGroup is the group whose members may change the state besides root
+const controlGroup = "selfcontrol"
+
+// isPrivileged decides what peers may do; replaced in tests, which can't
+// rely on the groups of the host
+var isPrivileged = privileged
+
+// Server serves a Service as JSON-RPC on a Unix socket
+type Server struct {
+	svc Service
+	ln  *net.UnixListener
+
+	// mu serializes changes from all connections
+	mu sync.Mutex
+}
+
+// Listen creates the control socket at path, replacing a stale one
+// Any local user may connect; peer credentials decide what they may do
+func Listen(path string, svc Service) (*Server, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0666); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	return &Server{svc: svc, ln: ln}, nil
+}
+
+// Serve accepts connections until Close is called
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.ln.AcceptUnix()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops accepting connections and removes the socket
+func (s *Server) Close() error {
+	return s.ln.Close()
+}
+
+// serveConn checks the peer's credentials and serves its requests
+func (s *Server) serveConn(conn *net.UnixConn) {
+	defer conn.Close()
+
+	uid, err := peerUID(conn)
+	if err != nil {
+		fmt.Printf("Rejecting control connection: %v\n", err)
+		return
+	}
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(rpcName, &api{svc: s.svc, mu: &s.mu, privileged: isPrivileged(uid)}); err != nil {
+		fmt.Printf("Error registering control API: %v\n", err)
+		return
+	}
+	srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+}
+
+// privileged reports whether the user may change the state: root and
+// members of the selfcontrol group
+func privileged(uid uint32) bool {
+	if uid == 0 {
+		return true
+	}
+
+	group, err := user.LookupGroup(controlGroup)
+	if err != nil {
+		return false
+	}
+	u, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return false
+	}
+	if u.Gid == group.Gid {
+		return true
+	}
+
+	gids, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	return slices.Contains(gids, group.Gid)
+}
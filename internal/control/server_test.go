@@ -0,0 +1,130 @@
+package control
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// fakeService answers Status and records the changes it is asked for; the
+// methods it doesn't override panic
+type fakeService struct {
+	Service
+
+	mu      sync.Mutex
+	changes []string
+}
+
+func (f *fakeService) Status() (*state.AppState, error) {
+	return &state.AppState{
+		CurrentProfile: state.DefaultProfileName,
+		ActiveSession: &state.Session{
+			EndTime:  time.Now().Add(time.Hour),
+			Profiles: []string{state.DefaultProfileName},
+			URLs:     []string{"reddit.com"},
+			Unlock:   &state.Unlock{ChallengeHash: "hash", RequestedAt: time.Now()},
+		},
+	}, nil
+}
+
+func (f *fakeService) record(change string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.changes = append(f.changes, change)
+	return nil
+}
+
+func (f *fakeService) AddURL(url string) error        { return f.record("AddURL") }
+func (f *fakeService) RemoveURLs(urls []string) error { return f.record("RemoveURLs") }
+func (f *fakeService) StartSession(time.Duration, string, []string) error {
+	return f.record("StartSession")
+}
+
+// serve serves svc on a temporary socket, treating every peer as privileged
+// or not, and returns a connected client
+func serve(t *testing.T, svc Service, privileged bool) *Client {
+	t.Helper()
+
+	old := isPrivileged
+	isPrivileged = func(uint32) bool { return privileged }
+	t.Cleanup(func() { isPrivileged = old })
+
+	path := filepath.Join(t.TempDir(), "control.sock")
+	server, err := Listen(path, svc)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	client, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestServerUnprivilegedPeer(t *testing.T) {
+	svc := &fakeService{}
+	client := serve(t, svc, false)
+
+	st, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if st.ActiveSession == nil || st.ActiveSession.Unlock != nil {
+		t.Errorf("session = %+v, want it without the unlock", st.ActiveSession)
+	}
+
+	changes := map[string]func() error{
+		"AddURL":       func() error { return client.AddURL("example.com") },
+		"RemoveURLs":   func() error { return client.RemoveURLs([]string{"reddit.com"}) },
+		"StartSession": func() error { return client.StartSession(time.Hour, "1 hour", nil) },
+	}
+	for name, change := range changes {
+		err := change()
+		if !errors.Is(err, errPermission) || !IsPermission(err) {
+			t.Errorf("%s = %v, want the permission error", name, err)
+		}
+	}
+	if len(svc.changes) != 0 {
+		t.Errorf("service was asked for %v", svc.changes)
+	}
+}
+
+func TestServerPrivilegedPeer(t *testing.T) {
+	svc := &fakeService{}
+	client := serve(t, svc, true)
+
+	st, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if st.ActiveSession == nil || st.ActiveSession.Unlock == nil {
+		t.Errorf("session = %+v, want it with the unlock", st.ActiveSession)
+	}
+
+	if err := client.AddURL("example.com"); err != nil {
+		t.Errorf("AddURL: %v", err)
+	}
+	if err := client.RemoveURLs([]string{"reddit.com"}); err != nil {
+		t.Errorf("RemoveURLs: %v", err)
+	}
+	if err := client.StartSession(time.Hour, "1 hour", nil); err != nil {
+		t.Errorf("StartSession: %v", err)
+	}
+	if len(svc.changes) != 3 {
+		t.Errorf("service was asked for %v, want 3 changes", svc.changes)
+	}
+}
+
+func TestPrivilegedRoot(t *testing.T) {
+	if !privileged(0) {
+		t.Error("root isn't privileged")
+	}
+}
@@ -0,0 +1,387 @@
+package daemon
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/clock"
+	"github.com/phil/selfcontrol/internal/control"
+	"github.com/phil/selfcontrol/internal/schedule"
+	"github.com/phil/selfcontrol/internal/state"
+	"github.com/phil/selfcontrol/internal/subscription"
+)
+
+// Daemon enforces blocking sessions in the background: it unblocks expired
+// sessions and re-applies rules that were tampered with
+type Daemon struct {
+	sinkhole *blocker.Sinkhole
+
+	// lastSession is the session seen in the previous cycle, used to notice
+	// a session being removed from the state file by hand
+	lastSession *state.Session
+
+	// sessionEnd is when the active session ends according to the trusted
+	// session clock, zero if there is none
+	sessionEnd time.Time
+
+	// nextSchedule is when the next scheduled window starts, zero if none
+	nextSchedule time.Time
+
+	// fetcher downloads subscribed lists; nextRefresh is when the next one
+	// is due, zero if there are none or a refresh is running
+	fetcher     *subscription.Fetcher
+	nextRefresh time.Time
+
+	// refreshing is set while lists are fetched in the background, which
+	// send the URLs of changed lists to refreshed when done
+	refreshing bool
+	refreshed  chan []string
+
+	// attempted is when each list was last fetched
+	attempted map[string]time.Time
+
+	// retry is when a failed check is tried again, zero after one succeeded
+	retry time.Time
+}
+
+// newBlocker selects the blocking backend and readClock reads the clocks;
+// both are replaced in tests
+var (
+	newBlocker = blocker.New
+	readClock  = clock.Read
+)
+
+// New creates a daemon, starting the control socket and the DNS sinkhole
+// if it is enabled
+func New() *Daemon {
+	d := &Daemon{
+		fetcher:   subscription.NewFetcher(),
+		refreshed: make(chan []string, 1),
+		attempted: make(map[string]time.Time),
+	}
+	d.startControl()
+	d.startSinkhole()
+	return d
+}
+
+// startControl serves the control API for the TUI and CLI
+func (d *Daemon) startControl() {
+	server, err := control.Listen(control.SocketPath, control.NewLocal())
+	if err != nil {
+		fmt.Printf("Error starting control socket: %v\n", err)
+		return
+	}
+
+	go func() {
+		if err := server.Serve(); err != nil {
+			fmt.Printf("Error serving control socket: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("Control socket: %s\n", control.SocketPath)
+}
+
+// Check intervals
+const (
+	// pollInterval is used when file changes can't be watched
+	pollInterval = 10 * time.Second
+
+	// safetyInterval catches changes that produce no file events, such as
+	// edits to nftables rules
+	safetyInterval = time.Minute
+)
+
+// Run enforces sessions forever, checking whenever the state or hosts file
+// changes and exactly when the active session ends, a schedule starts or a
+// subscribed list is due
+func (d *Daemon) Run() {
+	interval := safetyInterval
+	changes, err := watchFiles([]string{state.GetStatePath(), blocker.NewHostsBlocker().Path})
+	if err != nil {
+		fmt.Printf("Not watching files (%v), checking every %s\n", err, pollInterval)
+		interval = pollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.Check()
+
+		// Arm a one-shot timer for the end of the active session, the start
+		// of the next scheduled window or the next list refresh, whichever
+		// comes first
+		var expiry *time.Timer
+		var expired <-chan time.Time
+		if next := d.nextWakeup(); !next.IsZero() {
+			expiry = time.NewTimer(time.Until(next))
+			expired = expiry.C
+		}
+
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				// The watcher failed, keep going with polling only
+				changes = nil
+				ticker.Reset(pollInterval)
+			}
+		case changed := <-d.refreshed:
+			d.refreshing = false
+			d.applySubscriptions(changed)
+		case <-expired:
+		case <-ticker.C:
+		}
+
+		if expiry != nil {
+			expiry.Stop()
+		}
+	}
+}
+
+// nextWakeup returns the next time a check is due, zero if none is
+func (d *Daemon) nextWakeup() time.Time {
+	return earliest(earliest(earliest(d.sessionEnd, d.nextSchedule), d.nextRefresh), d.retry)
+}
+
+// Check runs a single enforcement cycle
+func (d *Daemon) Check() {
+	var current *state.AppState
+
+	// Resolve the hosts of a scheduled window that is due before locking
+	control.Prepare(func(st *state.AppState) error {
+		w, ok := schedule.Active(st.Schedules, time.Now())
+		switch {
+		case !ok || !w.End.After(st.ScheduledUntil):
+		case st.IsSessionActive():
+			st.AddSessionProfiles(w.Profiles)
+		default:
+			st.StartSessionUntil(w.End, "", w.Profiles)
+		}
+		return nil
+	})
+
+	err := state.Update(func(st *state.AppState) error {
+		b, err := newBlocker(st.Backend)
+		if err != nil {
+			return fmt.Errorf("failed to select blocker: %w", err)
+		}
+
+		d.restoreRemovedSession(st)
+		d.checkClock(st)
+
+		// Check if session expired or was unlocked
+		if reason, over := st.SessionOver(); over {
+			if reason == state.EndCancelled {
+				fmt.Println("Emergency unlock is due, unblocking...")
+			} else {
+				fmt.Printf("Session expired at %s, unblocking...\n", st.ActiveSession.EndTime)
+			}
+
+			// Unblock
+			if err := b.Unblock(); err != nil {
+				return fmt.Errorf("failed to unblock: %w", err)
+			}
+
+			// End session
+			if reason == state.EndCancelled {
+				st.RecordEvent(state.EventUnlock, "session ended by emergency unlock")
+			}
+			st.EndSession(reason)
+			fmt.Println("Successfully unblocked websites")
+		}
+
+		d.applySchedules(b, st)
+
+		if st.IsSessionActive() {
+			d.enforce(b, st)
+		}
+
+		current = st
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error: %v, retrying in %s\n", err, pollInterval)
+
+		// The wakeup times may have passed already, such as the end of a
+		// session that can't be unblocked; waiting for them would retry in
+		// a busy loop
+		d.sessionEnd, d.nextSchedule, d.nextRefresh = time.Time{}, time.Time{}, time.Time{}
+		d.retry = time.Now().Add(pollInterval)
+		return
+	}
+	d.retry = time.Time{}
+
+	if d.sinkhole != nil {
+		d.syncSinkhole(current)
+	}
+
+	d.lastSession = current.ActiveSession
+	d.sessionEnd = time.Time{}
+	if remaining := current.TimeRemaining(); remaining > 0 {
+		if unlock, ok := current.UnlockRemaining(); ok && unlock < remaining {
+			remaining = unlock
+		}
+
+		// time.Now carries a monotonic reading, so the timer isn't affected
+		// by later changes of the wall clock
+		d.sessionEnd = time.Now().Add(remaining)
+	}
+	d.nextSchedule, _ = schedule.Next(current.Schedules, time.Now())
+
+	d.refreshSubscriptions(current)
+}
+
+// applySchedules starts the session required by the schedules, or extends
+// the running one when a scheduled window overlaps it
+func (d *Daemon) applySchedules(b blocker.Blocker, st *state.AppState) {
+	now := time.Now()
+	w, ok := schedule.Active(st.Schedules, now)
+
+	if !ok {
+		return
+	}
+
+	// Windows already started once aren't started again, so a session that
+	// was ended early stays ended; a running session still takes the
+	// profiles of windows that began since
+	fresh := w.End.After(st.ScheduledUntil)
+	if !fresh && st.ActiveSession == nil {
+		return
+	}
+
+	var profiles []string
+	for _, name := range w.Profiles {
+		if st.Profile(name) != nil {
+			profiles = append(profiles, name)
+		}
+	}
+	if len(profiles) == 0 {
+		if fresh {
+			fmt.Printf("Scheduled profiles %v don't exist, skipping\n", w.Profiles)
+		}
+		return
+	}
+	if !fresh && !missingProfiles(st.ActiveSession, profiles) {
+		return
+	}
+	mode, err := st.ProfilesMode(profiles)
+	if err != nil {
+		fmt.Printf("Skipping schedule: %v\n", err)
+		return
+	}
+	if mode == state.ModeAllow && !blocker.CanAllow(b, d.sinkhole != nil) {
+		fmt.Printf("Skipping schedule for %v: %v\n", profiles, blocker.ErrAllowUnsupported)
+		return
+	}
+
+	if session := st.ActiveSession; session != nil {
+		if session.Allowlist() != (mode == state.ModeAllow) {
+			// Merging would turn blocked URLs into allowed ones or vice versa
+			fmt.Printf("Schedule for %v doesn't match the mode of the running session, skipping\n", profiles)
+			return
+		}
+		if w.End.After(session.EndTime) {
+			session.EndTime = w.End
+			session.Duration = "scheduled until " + w.End.Format("15:04")
+			session.Scheduled = true
+		}
+		st.AddSessionProfiles(profiles)
+		fmt.Printf("Schedule extends the session until %s\n", session.EndTime.Format(time.DateTime))
+	} else {
+		st.StartSessionUntil(w.End, "scheduled until "+w.End.Format("15:04"), profiles)
+		st.ActiveSession.Scheduled = true
+		fmt.Printf("Schedule started a session until %s\n", w.End.Format(time.DateTime))
+	}
+	if w.End.After(st.ScheduledUntil) {
+		st.ScheduledUntil = w.End
+	}
+
+	if err := blocker.Apply(b, st.SessionURLs(), st.ActiveSession.Allowlist()); err != nil {
+		fmt.Printf("Error applying scheduled blocking rules: %v\n", err)
+	}
+}
+
+// missingProfiles reports whether the session doesn't enforce some of the
+// profiles
+func missingProfiles(session *state.Session, profiles []string) bool {
+	for _, name := range profiles {
+		if !slices.Contains(session.Profiles, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreRemovedSession puts back a running session that disappeared from
+// the state file before its end time
+func (d *Daemon) restoreRemovedSession(st *state.AppState) {
+	last := d.lastSession
+	if last == nil || st.ActiveSession != nil || last.Remaining(readClock()) <= 0 {
+		return
+	}
+	if remaining, ok := last.UnlockRemaining(readClock()); ok && remaining == 0 {
+		// Ended by an emergency unlock
+		return
+	}
+
+	fmt.Println("Active session was removed from the state file, restoring it...")
+
+	st.ActiveSession = last
+	st.ActiveSession.TamperCount++
+	st.RecordEvent(state.EventTamper, "session removed from state file")
+}
+
+// checkClock keeps the active session's clock checkpoint on the current boot
+// and records changes of the system clock, which the session then ignores
+func (d *Daemon) checkClock(st *state.AppState) {
+	session := st.ActiveSession
+	if session == nil {
+		return
+	}
+
+	r := readClock()
+	if session.Checkpoint == nil || session.Checkpoint.BootID != r.BootID {
+		// After a reboot, elapsed time can only be tracked from now on
+		if r.BootID != "" {
+			session.Checkpoint = &r
+			session.ClockSkew = 0
+		}
+		return
+	}
+
+	_, skew := session.Now(r)
+	if (skew - session.ClockSkew).Abs() <= state.ClockTolerance {
+		return
+	}
+
+	fmt.Printf("System clock is off by %s from the time elapsed since boot, ignoring it for the session\n", skew.Round(time.Second))
+
+	session.ClockSkew = skew
+	session.TamperCount++
+	st.RecordEvent(state.EventTamper, fmt.Sprintf("system clock changed, off by %s", skew.Round(time.Second)))
+}
+
+// enforce re-applies the blocking rules if they are missing or were changed
+func (d *Daemon) enforce(b blocker.Blocker, st *state.AppState) {
+	allow := st.ActiveSession.Allowlist()
+	ok, err := blocker.Check(b, st.SessionURLs(), allow)
+	if err != nil {
+		fmt.Printf("Error verifying blocking rules: %v\n", err)
+		return
+	}
+	if ok {
+		return
+	}
+
+	fmt.Println("Blocking rules were modified, re-applying...")
+
+	if err := blocker.Apply(b, st.SessionURLs(), allow); err != nil {
+		fmt.Printf("Error re-applying blocking rules: %v\n", err)
+		return
+	}
+
+	st.ActiveSession.TamperCount++
+	st.RecordEvent(state.EventTamper, "blocking rules modified, re-applied")
+}
@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/clock"
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// fakeBlocker records calls and fails Unblock if unblockErr is set
+type fakeBlocker struct {
+	blocked    bool
+	unblockErr error
+	unblocks   int
+}
+
+func (f *fakeBlocker) Block(urls []string) error {
+	f.blocked = true
+	return nil
+}
+
+func (f *fakeBlocker) Unblock() error {
+	f.unblocks++
+	if f.unblockErr != nil {
+		return f.unblockErr
+	}
+	f.blocked = false
+	return nil
+}
+
+func (f *fakeBlocker) IsBlocked() (bool, error)           { return f.blocked, nil }
+func (f *fakeBlocker) Verify(urls []string) (bool, error) { return f.blocked, nil }
+
+// newTestDaemon returns a daemon using b, without the control socket and
+// sinkhole, and keeps the state in a temporary directory
+func newTestDaemon(t *testing.T, b blocker.Blocker) *Daemon {
+	t.Helper()
+
+	state.SetDir(t.TempDir())
+	old := newBlocker
+	newBlocker = func(string) (blocker.Blocker, error) { return b, nil }
+	t.Cleanup(func() { newBlocker = old })
+
+	return &Daemon{
+		refreshed: make(chan []string, 1),
+		attempted: make(map[string]time.Time),
+	}
+}
+
+func TestCheckUnblocksExpiredSession(t *testing.T) {
+	b := &fakeBlocker{blocked: true}
+	d := newTestDaemon(t, b)
+
+	if err := state.Update(func(st *state.AppState) error {
+		st.StartSessionUntil(time.Now().Add(-time.Minute), "expired", nil)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Check()
+
+	if b.blocked {
+		t.Error("expired session is still blocked")
+	}
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ActiveSession != nil {
+		t.Errorf("expired session wasn't ended: %+v", st.ActiveSession)
+	}
+	if !d.nextWakeup().IsZero() {
+		t.Errorf("wakeup armed at %s without a session", d.nextWakeup())
+	}
+}
+
+func TestCheckBacksOffWhenUnblockFails(t *testing.T) {
+	b := &fakeBlocker{blocked: true, unblockErr: errors.New("hosts file is immutable")}
+	d := newTestDaemon(t, b)
+
+	if err := state.Update(func(st *state.AppState) error {
+		st.StartSessionUntil(time.Now().Add(-time.Minute), "expired", nil)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		before := time.Now()
+		d.Check()
+
+		// The session end has passed, but the next check waits
+		if next := d.nextWakeup(); next.Before(before.Add(pollInterval)) {
+			t.Fatalf("check %d: next wakeup in %s, want at least %s", i, next.Sub(before), pollInterval)
+		}
+	}
+	if b.unblocks != 3 {
+		t.Errorf("Unblock called %d times, want 3", b.unblocks)
+	}
+
+	// The session stays until it can be unblocked
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ActiveSession == nil {
+		t.Fatal("session was ended although unblocking failed")
+	}
+
+	b.unblockErr = nil
+	d.Check()
+	if b.blocked || !d.retry.IsZero() {
+		t.Errorf("recovered check: blocked %v, retry at %s", b.blocked, d.retry)
+	}
+}
+
+func TestCheckClock(t *testing.T) {
+	start := time.Date(2026, time.October, 5, 9, 0, 0, 0, time.UTC)
+	checkpoint := clock.Reading{Wall: start, Uptime: 100 * time.Second, BootID: "boot-a"}
+
+	var now clock.Reading
+	old := readClock
+	readClock = func() clock.Reading { return now }
+	t.Cleanup(func() { readClock = old })
+
+	cp := checkpoint
+	st := &state.AppState{ActiveSession: &state.Session{StartTime: start, EndTime: start.Add(time.Hour), Checkpoint: &cp}}
+	d := &Daemon{}
+
+	// A wall clock within the tolerance is trusted
+	now = clock.Reading{Wall: start.Add(11 * time.Minute), Uptime: cp.Uptime + 10*time.Minute, BootID: "boot-a"}
+	d.checkClock(st)
+	if st.ActiveSession.TamperCount != 0 || st.ActiveSession.ClockSkew != 0 || len(st.Events) != 0 {
+		t.Fatalf("drift within tolerance: %+v, events %v", st.ActiveSession, st.Events)
+	}
+
+	// A jump forward is recorded once and doesn't shorten the session
+	now = clock.Reading{Wall: start.Add(10*time.Minute + 2*time.Hour), Uptime: cp.Uptime + 10*time.Minute, BootID: "boot-a"}
+	d.checkClock(st)
+	d.checkClock(st)
+	if st.ActiveSession.TamperCount != 1 || st.ActiveSession.ClockSkew != 2*time.Hour {
+		t.Errorf("clock jump: tamper count %d, skew %s; want 1, 2h", st.ActiveSession.TamperCount, st.ActiveSession.ClockSkew)
+	}
+	if len(st.Events) != 1 || st.Events[0].Kind != state.EventTamper {
+		t.Errorf("clock jump events = %v", st.Events)
+	}
+	if remaining := st.ActiveSession.Remaining(now); remaining != 50*time.Minute {
+		t.Errorf("remaining after clock jump = %s, want 50m", remaining)
+	}
+
+	// After a reboot the session takes a new checkpoint on the wall clock
+	now = clock.Reading{Wall: start.Add(20 * time.Minute), Uptime: 30 * time.Second, BootID: "boot-b"}
+	d.checkClock(st)
+	if got := st.ActiveSession.Checkpoint; got == nil || *got != now {
+		t.Errorf("checkpoint after reboot = %+v, want %+v", got, now)
+	}
+	if st.ActiveSession.ClockSkew != 0 {
+		t.Errorf("skew after reboot = %s, want 0", st.ActiveSession.ClockSkew)
+	}
+	if remaining := st.ActiveSession.Remaining(now); remaining != 40*time.Minute {
+		t.Errorf("remaining after reboot = %s, want 40m", remaining)
+	}
+
+	// Without a boot clock the checkpoint is kept
+	now = clock.Reading{Wall: start.Add(25 * time.Minute)}
+	before := *st.ActiveSession.Checkpoint
+	d.checkClock(st)
+	if *st.ActiveSession.Checkpoint != before {
+		t.Errorf("checkpoint replaced without a boot clock")
+	}
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// startSinkhole starts the local DNS sinkhole if it is enabled in the state
+func (d *Daemon) startSinkhole() {
+	st, err := state.Load()
+	if err != nil {
+		fmt.Printf("Error loading state: %v\n", err)
+		return
+	}
+	if st.DNS == nil || !st.DNS.Enabled {
+		return
+	}
+
+	d.sinkhole = blocker.NewSinkhole(st.DNS.Listen, st.DNS.Upstream)
+	go func() {
+		if err := d.sinkhole.ListenAndServe(); err != nil {
+			fmt.Printf("Error running DNS sinkhole: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("DNS sinkhole listening on %s, forwarding to %s\n", d.sinkhole.Listen, d.sinkhole.Upstream)
+}
+
+// syncSinkhole makes the sinkhole block the URLs while a session is active,
+// or everything else during an allowlist session
+func (d *Daemon) syncSinkhole(st *state.AppState) {
+	var err error
+	if st.IsSessionActive() {
+		err = blocker.Apply(d.sinkhole, st.SessionURLs(), st.ActiveSession.Allowlist())
+	} else {
+		err = d.sinkhole.Unblock()
+	}
+
+	if err != nil {
+		fmt.Printf("Error updating DNS sinkhole: %v\n", err)
+	}
+}
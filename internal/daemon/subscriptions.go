@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/control"
+	"github.com/phil/selfcontrol/internal/state"
+	"github.com/phil/selfcontrol/internal/subscription"
+)
+
+// refreshSubscriptions starts fetching the subscribed lists that are due in
+// the background and computes when the next one is due
+// Fetching can take a while when a list's host is slow or unreachable, so
+// it never holds up enforcement; Run applies the results once they arrive
+// on d.refreshed.
+func (d *Daemon) refreshSubscriptions(st *state.AppState) {
+	if d.refreshing {
+		return
+	}
+
+	now := time.Now()
+	d.nextRefresh = time.Time{}
+
+	var due []state.Subscription
+	for _, sub := range subscriptions(st) {
+		cache, err := state.LoadSubscriptionCache(sub.URL)
+		if err != nil {
+			fmt.Printf("Error reading cache of %s: %v\n", sub.URL, err)
+		}
+
+		// Retry later even if the cache couldn't be written last time
+		next := subscription.Due(sub, cache)
+		if attempted, ok := d.attempted[sub.URL]; ok {
+			next = latest(next, attempted.Add(subscription.RetryInterval))
+		}
+
+		if next.After(now) {
+			d.nextRefresh = earliest(d.nextRefresh, next)
+			continue
+		}
+		due = append(due, sub)
+		d.attempted[sub.URL] = now
+	}
+
+	if len(due) > 0 {
+		d.refreshing = true
+		go d.fetch(due)
+	}
+}
+
+// fetch refreshes lists and sends the URLs of those that changed to
+// d.refreshed; lists that can't be fetched keep their cached copy
+func (d *Daemon) fetch(subs []state.Subscription) {
+	var changed []string
+	for _, sub := range subs {
+		updated, err := d.fetcher.Refresh(sub.URL)
+		switch {
+		case err != nil:
+			fmt.Printf("Error refreshing %s, using the cached copy: %v\n", sub.URL, err)
+		case updated:
+			fmt.Printf("Subscribed list %s changed\n", sub.URL)
+			changed = append(changed, sub.URL)
+		}
+	}
+	d.refreshed <- changed
+}
+
+// applySubscriptions merges the domains of changed lists into the active
+// session and re-applies its rules if any were new
+func (d *Daemon) applySubscriptions(changed []string) {
+	var current *state.AppState
+	applied := false
+
+	// Resolve the new domains before locking
+	control.Prepare(func(st *state.AppState) error {
+		for _, listURL := range changed {
+			if _, err := st.MergeSubscription(listURL); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	err := state.Update(func(st *state.AppState) error {
+		current = st
+		if !st.IsSessionActive() || st.ActiveSession.Allowlist() {
+			return nil
+		}
+
+		added := false
+		for _, listURL := range changed {
+			ok, err := st.MergeSubscription(listURL)
+			if err != nil {
+				return err
+			}
+			added = added || ok
+		}
+		if !added {
+			return nil
+		}
+
+		b, err := newBlocker(st.Backend)
+		if err != nil {
+			return fmt.Errorf("failed to select blocker: %w", err)
+		}
+		if err := blocker.Apply(b, st.SessionURLs(), false); err != nil {
+			return fmt.Errorf("failed to apply subscribed lists: %w", err)
+		}
+		applied = true
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if applied && d.sinkhole != nil {
+		d.syncSinkhole(current)
+	}
+}
+
+// subscriptions returns the lists followed by any profile, each with the
+// shortest refresh interval asked for
+func subscriptions(st *state.AppState) []state.Subscription {
+	var subs []state.Subscription
+	for _, p := range st.Profiles {
+		for _, sub := range p.Subscriptions {
+			i := slices.IndexFunc(subs, func(s state.Subscription) bool { return s.URL == sub.URL })
+			switch {
+			case i < 0:
+				subs = append(subs, sub)
+			case sub.Interval() < subs[i].Interval():
+				subs[i] = sub
+			}
+		}
+	}
+	return subs
+}
+
+// earliest returns the earlier of two times, ignoring zero ones
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// latest returns the later of two times
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
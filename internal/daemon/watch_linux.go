@@ -0,0 +1,97 @@
+//go:build linux
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchFiles reports changes to any of the files on the returned channel
+//
+// The parent directories are watched rather than the files themselves, so
+// files replaced by an atomic rename keep being watched.
+func watchFiles(paths []string) (<-chan struct{}, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	const mask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_CREATE | unix.IN_DELETE
+
+	// Map watch descriptors to the names we care about in that directory
+	names := make(map[int32]map[string]bool)
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+
+		wd, err := unix.InotifyAddWatch(fd, dir, mask)
+		if err != nil {
+			unix.Close(fd)
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+
+		if names[int32(wd)] == nil {
+			names[int32(wd)] = make(map[string]bool)
+		}
+		names[int32(wd)][filepath.Base(path)] = true
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer unix.Close(fd)
+
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			n, err := unix.Read(fd, buf)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil || n <= 0 {
+				fmt.Printf("Error reading file events: %v\n", err)
+				close(changes)
+				return
+			}
+
+			changed := false
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+				name := string(nameBytes[:clen(nameBytes)])
+
+				if names[event.Wd][name] || event.Mask&unix.IN_Q_OVERFLOW != 0 {
+					changed = true
+				}
+				offset += unix.SizeofInotifyEvent + int(event.Len)
+			}
+
+			if changed {
+				// Coalesce bursts of events into a single notification
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// clen returns the length of a NUL-terminated name
+func clen(b []byte) int {
+	for i, c := range b {
+		if c == 0 {
+			return i
+		}
+	}
+	return len(b)
+}
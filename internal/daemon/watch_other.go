@@ -0,0 +1,11 @@
+//go:build !linux
+
+package daemon
+
+import "errors"
+
+// watchFiles is only implemented with inotify on Linux; elsewhere the
+// daemon falls back to polling
+func watchFiles(paths []string) (<-chan struct{}, error) {
+	return nil, errors.New("file watching is not supported on this platform")
+}
@@ -0,0 +1,201 @@
+package schedule
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// Window is a period in which schedules require blocking
+type Window struct {
+	Start    time.Time
+	End      time.Time
+	Profiles []string
+}
+
+// lookahead bounds how far Next searches; every schedule recurs weekly
+const lookahead = 8
+
+// dayNames maps the accepted day names to weekdays
+var dayNames = map[string][]time.Weekday{
+	"sun": {time.Sunday}, "sunday": {time.Sunday},
+	"mon": {time.Monday}, "monday": {time.Monday},
+	"tue": {time.Tuesday}, "tuesday": {time.Tuesday},
+	"wed": {time.Wednesday}, "wednesday": {time.Wednesday},
+	"thu": {time.Thursday}, "thursday": {time.Thursday},
+	"fri": {time.Friday}, "friday": {time.Friday},
+	"sat": {time.Saturday}, "saturday": {time.Saturday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+	"daily":    {time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+}
+
+// parsed is a validated schedule
+type parsed struct {
+	days       map[time.Weekday]bool
+	start, end time.Duration // offsets from midnight
+	profile    string
+}
+
+// Validate checks that a schedule has known days, valid times and a profile
+func Validate(s state.Schedule) error {
+	_, err := parse(s)
+	return err
+}
+
+func parse(s state.Schedule) (parsed, error) {
+	p := parsed{days: map[time.Weekday]bool{}, profile: s.Profile}
+
+	if len(s.Days) == 0 {
+		return p, fmt.Errorf("schedule has no days")
+	}
+	for _, name := range s.Days {
+		days, ok := dayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return p, fmt.Errorf("unknown day %q", name)
+		}
+		for _, d := range days {
+			p.days[d] = true
+		}
+	}
+
+	var err error
+	if p.start, err = parseClock(s.Start); err != nil {
+		return p, err
+	}
+	if p.end, err = parseClock(s.End); err != nil {
+		return p, err
+	}
+	if p.start == p.end {
+		return p, fmt.Errorf("schedule starts and ends at %s", s.Start)
+	}
+
+	if s.Profile == "" {
+		return p, fmt.Errorf("schedule has no profile")
+	}
+	return p, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// windows returns the windows of all valid schedules that start on the days
+// from `from` to `to` days after now's date (negative for earlier days)
+func windows(schedules []state.Schedule, now time.Time, from, to int) []Window {
+	var result []Window
+	for _, s := range schedules {
+		p, err := parse(s)
+		if err != nil {
+			continue
+		}
+
+		for offset := from; offset <= to; offset++ {
+			day := time.Date(now.Year(), now.Month(), now.Day()+offset, 0, 0, 0, 0, now.Location())
+			if !p.days[day.Weekday()] {
+				continue
+			}
+
+			// Build wall-clock times so DST shifts don't move the window
+			start := atClock(day, p.start)
+			end := atClock(day, p.end)
+			if p.end < p.start {
+				end = atClock(day.AddDate(0, 0, 1), p.end)
+			}
+			result = append(result, Window{Start: start, End: end, Profiles: []string{p.profile}})
+		}
+	}
+	return result
+}
+
+// atClock returns the wall-clock time offset after midnight of day
+func atClock(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+// Active returns the window that covers now, if any
+// Windows that have started and overlap now are merged, enforcing the union
+// of their profiles. The result ends when the last of them ends, extended
+// through later windows that chain onto it without adding profiles; windows
+// with other profiles are merged once they begin.
+func Active(schedules []state.Schedule, now time.Time) (Window, bool) {
+	// Windows may have started the day before (overnight) and chained
+	// windows may run into the following days
+	all := windows(schedules, now, -1, lookahead)
+
+	var active Window
+	found := false
+	for _, w := range all {
+		if w.Start.After(now) || !now.Before(w.End) {
+			continue
+		}
+		if !found {
+			active, found = Window{Start: w.Start, End: w.End}, true
+		}
+		active = merge(active, w)
+	}
+	if !found {
+		return Window{}, false
+	}
+
+	// Extend through windows of the same profiles that start before the
+	// merged window ends
+	for changed := true; changed; {
+		changed = false
+		for _, w := range all {
+			if w.Start.After(active.End) || !w.End.After(active.End) || !containsAll(active.Profiles, w.Profiles) {
+				continue
+			}
+			active.End = w.End
+			changed = true
+		}
+	}
+
+	slices.Sort(active.Profiles)
+	return active, true
+}
+
+// merge widens a to cover w and adds w's profiles
+func merge(a, w Window) Window {
+	if w.Start.Before(a.Start) {
+		a.Start = w.Start
+	}
+	if w.End.After(a.End) {
+		a.End = w.End
+	}
+	for _, p := range w.Profiles {
+		if !slices.Contains(a.Profiles, p) {
+			a.Profiles = append(a.Profiles, p)
+		}
+	}
+	return a
+}
+
+func containsAll(have, want []string) bool {
+	for _, p := range want {
+		if !slices.Contains(have, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Next returns the start of the first window beginning after now
+func Next(schedules []state.Schedule, now time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, w := range windows(schedules, now, 0, lookahead) {
+		if w.Start.After(now) && (next.IsZero() || w.Start.Before(next)) {
+			next = w.Start
+		}
+	}
+	return next, !next.IsZero()
+}
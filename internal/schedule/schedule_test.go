@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// at returns a time on a date in October 2026 in loc; the 5th is a Monday
+func at(loc *time.Location, day, hour, min int) time.Time {
+	return time.Date(2026, time.October, day, hour, min, 0, 0, loc)
+}
+
+func TestActive(t *testing.T) {
+	loc := time.UTC
+	work := state.Schedule{Days: []string{"weekdays"}, Start: "09:00", End: "12:00", Profile: "work"}
+	later := state.Schedule{Days: []string{"weekdays"}, Start: "11:00", End: "13:00", Profile: "b"}
+	afternoon := state.Schedule{Days: []string{"weekdays"}, Start: "12:00", End: "14:00", Profile: "work"}
+	night := state.Schedule{Days: []string{"mon"}, Start: "22:00", End: "06:00", Profile: "sleep"}
+
+	tests := []struct {
+		name      string
+		schedules []state.Schedule
+		now       time.Time
+		ok        bool
+		start     time.Time
+		end       time.Time
+		profiles  []string
+	}{
+		{"before", []state.Schedule{work}, at(loc, 5, 8, 59), false, time.Time{}, time.Time{}, nil},
+		{"start is inclusive", []state.Schedule{work}, at(loc, 5, 9, 0), true, at(loc, 5, 9, 0), at(loc, 5, 12, 0), []string{"work"}},
+		{"end is exclusive", []state.Schedule{work}, at(loc, 5, 12, 0), false, time.Time{}, time.Time{}, nil},
+		{"weekend", []state.Schedule{work}, at(loc, 10, 10, 0), false, time.Time{}, time.Time{}, nil},
+
+		// A reboot mid-window finds the window again from the clock alone
+		{"mid-window", []state.Schedule{work}, at(loc, 7, 10, 30), true, at(loc, 7, 9, 0), at(loc, 7, 12, 0), []string{"work"}},
+
+		// Overnight windows run from the day they start into the next one
+		{"overnight evening", []state.Schedule{night}, at(loc, 5, 23, 0), true, at(loc, 5, 22, 0), at(loc, 6, 6, 0), []string{"sleep"}},
+		{"overnight morning", []state.Schedule{night}, at(loc, 6, 2, 0), true, at(loc, 5, 22, 0), at(loc, 6, 6, 0), []string{"sleep"}},
+		{"overnight other day", []state.Schedule{night}, at(loc, 7, 2, 0), false, time.Time{}, time.Time{}, nil},
+
+		// Windows that haven't begun don't add their profiles yet
+		{"overlap before second", []state.Schedule{work, later}, at(loc, 5, 10, 30), true, at(loc, 5, 9, 0), at(loc, 5, 12, 0), []string{"work"}},
+		{"overlap after second", []state.Schedule{work, later}, at(loc, 5, 11, 30), true, at(loc, 5, 9, 0), at(loc, 5, 13, 0), []string{"b", "work"}},
+		{"overlap first ended", []state.Schedule{work, later}, at(loc, 5, 12, 30), true, at(loc, 5, 11, 0), at(loc, 5, 13, 0), []string{"b"}},
+
+		// Back-to-back windows of the same profile form one window
+		{"chained", []state.Schedule{work, afternoon}, at(loc, 5, 10, 0), true, at(loc, 5, 9, 0), at(loc, 5, 14, 0), []string{"work"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, ok := Active(tt.schedules, tt.now)
+			if ok != tt.ok {
+				t.Fatalf("Active = %+v, %v; want ok %v", w, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if !w.Start.Equal(tt.start) || !w.End.Equal(tt.end) || !slices.Equal(w.Profiles, tt.profiles) {
+				t.Errorf("Active = %s-%s %v, want %s-%s %v", w.Start, w.End, w.Profiles, tt.start, tt.end, tt.profiles)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	loc := time.UTC
+	work := state.Schedule{Days: []string{"weekdays"}, Start: "09:00", End: "12:00", Profile: "work"}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"later today", at(loc, 5, 8, 0), at(loc, 5, 9, 0)},
+		{"during window", at(loc, 5, 10, 0), at(loc, 6, 9, 0)},
+		{"over the weekend", at(loc, 9, 13, 0), at(loc, 12, 9, 0)},
+	}
+	for _, tt := range tests {
+		if got, ok := Next([]state.Schedule{work}, tt.now); !ok || !got.Equal(tt.want) {
+			t.Errorf("%s: Next = %s, %v; want %s", tt.name, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := Next([]state.Schedule{{Days: []string{"someday"}, Start: "09:00", End: "10:00", Profile: "x"}}, at(loc, 5, 8, 0)); ok {
+		t.Error("Next of an invalid schedule found a window")
+	}
+}
+
+func TestActiveAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clocks go back from 03:00 to 02:00 on Sunday, October 25th 2026; the
+	// window still ends at 07:00 wall-clock time, an hour longer than usual
+	night := state.Schedule{Days: []string{"sat"}, Start: "22:00", End: "07:00", Profile: "sleep"}
+	w, ok := Active([]state.Schedule{night}, at(berlin, 25, 4, 0))
+	if !ok {
+		t.Fatal("no active window")
+	}
+	if !w.End.Equal(at(berlin, 25, 7, 0)) {
+		t.Errorf("End = %s, want 07:00", w.End)
+	}
+	if got := w.End.Sub(w.Start); got != 10*time.Hour {
+		t.Errorf("window lasts %s, want 10h", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		schedule state.Schedule
+		ok       bool
+	}{
+		{state.Schedule{Days: []string{"Mon", "weekends"}, Start: "09:00", End: "17:30", Profile: "work"}, true},
+		{state.Schedule{Days: nil, Start: "09:00", End: "10:00", Profile: "work"}, false},
+		{state.Schedule{Days: []string{"funday"}, Start: "09:00", End: "10:00", Profile: "work"}, false},
+		{state.Schedule{Days: []string{"mon"}, Start: "9am", End: "10:00", Profile: "work"}, false},
+		{state.Schedule{Days: []string{"mon"}, Start: "09:00", End: "09:00", Profile: "work"}, false},
+		{state.Schedule{Days: []string{"mon"}, Start: "09:00", End: "10:00"}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.schedule); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v, want ok %v", tt.schedule, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// historyPath is the append-only session log next to the state file
+var historyPath = "/var/lib/selfcontrol/history.jsonl"
+
+// End reasons of a session
+const (
+	EndExpired   = "expired"
+	EndCancelled = "cancelled"
+	EndTampered  = "tampered"
+)
+
+// HistoryEntry records one blocking session
+// A line is appended when a session starts and another one when it ends;
+// EndReason is empty while the session runs.
+type HistoryEntry struct {
+	Start      time.Time `json:"start"`
+	PlannedEnd time.Time `json:"planned_end"`
+	ActualEnd  time.Time `json:"actual_end,omitempty"`
+	Profiles   []string  `json:"profiles"`
+
+	// URLs are the patterns the session enforced
+	URLs []string `json:"urls,omitempty"`
+
+	EndReason   string `json:"end_reason,omitempty"`
+	TamperCount int    `json:"tamper_count,omitempty"`
+}
+
+// Focused returns how long the session blocked, up to now for running ones
+func (e HistoryEntry) Focused(now time.Time) time.Duration {
+	end := e.ActualEnd
+	if end.IsZero() {
+		end = e.PlannedEnd
+		if now.Before(end) {
+			end = now
+		}
+	}
+	return max(end.Sub(e.Start), 0)
+}
+
+// GetHistoryPath returns the session history file path
+func GetHistoryPath() string {
+	return historyPath
+}
+
+// historyEntry builds the history line for the active session
+func (s *AppState) historyEntry() HistoryEntry {
+	return HistoryEntry{
+		Start:       s.ActiveSession.StartTime,
+		PlannedEnd:  s.ActiveSession.EndTime,
+		Profiles:    s.ActiveSession.Profiles,
+		URLs:        s.ActiveSession.URLs,
+		TamperCount: s.ActiveSession.TamperCount,
+	}
+}
+
+// appendHistory appends entries to the history file; the caller must hold
+// the exclusive state lock
+func appendHistory(entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(historyPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
+
+// LoadHistory returns all recorded sessions, oldest first
+// A session that started but has no end line and isn't the active session
+// disappeared without being ended properly and is reported as tampered.
+func LoadHistory(active *Session) ([]HistoryEntry, error) {
+	f, err := os.Open(historyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	// Later lines for the same session replace earlier ones
+	var entries []HistoryEntry
+	index := make(map[time.Time]int)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Skip a line torn by a crash
+			continue
+		}
+		key := e.Start.UTC()
+		if i, ok := index[key]; ok {
+			entries[i] = e
+			continue
+		}
+		index[key] = len(entries)
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	for i, e := range entries {
+		if e.EndReason != "" || (active != nil && active.StartTime.Equal(e.Start)) {
+			continue
+		}
+		entries[i].EndReason = EndTampered
+	}
+
+	slices.SortStableFunc(entries, func(a, b HistoryEntry) int {
+		return a.Start.Compare(b.Start)
+	})
+	return entries, nil
+}
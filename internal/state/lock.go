@@ -0,0 +1,50 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockState takes an advisory flock on the state's lock file and returns a
+// function that releases it
+//
+// A separate lock file is used because Update replaces the state file by
+// renaming, which would leave a lock on the old file behind. Readers without
+// permission to create the lock file proceed without a lock; they can't
+// write the state anyway.
+func lockState(how int) (func(), error) {
+	// Ensure config directory exists
+	dir := filepath.Dir(statePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	lockPath := statePath + ".lock"
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		f, err = os.Open(lockPath)
+	}
+	if err != nil {
+		if how == syscall.LOCK_SH && (os.IsPermission(err) || os.IsNotExist(err)) {
+			return func() {}, nil
+		}
+		return nil, err
+	}
+
+	for {
+		err = syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,200 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"syscall"
+)
+
+// CurrentSchemaVersion is the state file layout written by this version
+const CurrentSchemaVersion = 9
+
+// migrations[i] upgrades a raw state file from schema version i to i+1
+// Append a step here whenever the layout of AppState changes.
+var migrations = []func(raw map[string]json.RawMessage) error{
+	// 0 -> 1: files from before schema versioning; the layout is unchanged
+	func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+
+	// 1 -> 2: the flat URL list becomes the "default" profile
+	func(raw map[string]json.RawMessage) error {
+		var urls []string
+		if data, ok := raw["urls"]; ok {
+			if err := json.Unmarshal(data, &urls); err != nil {
+				return err
+			}
+		}
+		if urls == nil {
+			urls = []string{}
+		}
+
+		profiles, err := json.Marshal([]*Profile{{Name: DefaultProfileName, URLs: urls}})
+		if err != nil {
+			return err
+		}
+		raw["profiles"] = profiles
+		raw["current_profile"], _ = json.Marshal(DefaultProfileName)
+		delete(raw, "urls")
+
+		// A running session enforced the flat list
+		if data, ok := raw["active_session"]; ok && string(data) != "null" {
+			var session map[string]json.RawMessage
+			if err := json.Unmarshal(data, &session); err != nil {
+				return err
+			}
+			session["profiles"], _ = json.Marshal([]string{DefaultProfileName})
+			if raw["active_session"], err = json.Marshal(session); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+
+	// 2 -> 3: schedules were added; older files have none
+	func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+
+	// 3 -> 4: sessions record their time zone; older ones use local time
+	func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+
+	// 4 -> 5: sessions record clock checkpoints; the daemon adds one to a
+	// running session
+	func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+
+	// 5 -> 6: sessions keep their own list of enforced URLs, which a running
+	// session takes from its profiles
+	func(raw map[string]json.RawMessage) error {
+		data, ok := raw["active_session"]
+		if !ok || string(data) == "null" {
+			return nil
+		}
+
+		var session map[string]json.RawMessage
+		if err := json.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		var names []string
+		if err := json.Unmarshal(session["profiles"], &names); err != nil {
+			return err
+		}
+		st := &AppState{}
+		if err := json.Unmarshal(raw["profiles"], &st.Profiles); err != nil {
+			return err
+		}
+
+		var err error
+		session["urls"], _ = json.Marshal(st.ProfileURLs(names))
+		raw["active_session"], err = json.Marshal(session)
+		return err
+	},
+
+	// 6 -> 7: emergency unlocks were added; older files have none pending
+	func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+
+	// 7 -> 8: profiles and sessions have a mode; older ones block their URLs
+	func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+
+	// 8 -> 9: profiles can subscribe to remote lists and sessions copy their
+	// domains; older ones have none
+	func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+}
+
+// migrate runs the migration chain on a raw state file
+// It refuses files written by a newer version, which this binary would
+// silently drop fields from.
+func migrate(data []byte) ([]byte, bool, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false, err
+	}
+
+	version := 0
+	if v, ok := raw["schema_version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, false, fmt.Errorf("invalid schema_version: %w", err)
+		}
+	}
+
+	if version > CurrentSchemaVersion {
+		return nil, false, fmt.Errorf("state file has schema version %d, but this version of selfcontrol only supports up to %d; please upgrade", version, CurrentSchemaVersion)
+	}
+	if version == CurrentSchemaVersion {
+		return data, false, nil
+	}
+
+	for ; version < CurrentSchemaVersion; version++ {
+		if err := migrations[version](raw); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate state from schema version %d: %w", version, err)
+		}
+	}
+
+	raw["schema_version"], _ = json.Marshal(CurrentSchemaVersion)
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// LegacyStatePath returns the state file used by earlier versions,
+// $HOME/.config/selfcontrol-tui/state.json, if it exists
+// When run through sudo, the invoking user's home directory is used. The
+// daemon has neither, so clients look the file up and hand its contents to
+// ImportLegacy.
+func LegacyStatePath() string {
+	home, err := os.UserHomeDir()
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		if u, lookupErr := user.Lookup(sudoUser); lookupErr == nil {
+			home, err = u.HomeDir, nil
+		}
+	}
+	if err != nil || home == "" {
+		return ""
+	}
+
+	path := filepath.Join(home, ".config", "selfcontrol-tui", "state.json")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// ImportLegacy makes a legacy state file the current state, migrating it
+// to the current schema version
+// It does nothing and returns false if a state file already exists.
+func ImportLegacy(data []byte) (bool, error) {
+	unlock, err := lockState(syscall.LOCK_EX)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		return false, err
+	}
+
+	state, _, err := parse(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to read legacy state: %w", err)
+	}
+	if err := save(state); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,99 @@
+package state
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// v0State is a state file from before schema versioning, with a flat URL
+// list and a running session
+var v0State = []byte(`{
+	"urls": ["youtube.com", "reddit.com"],
+	"active_session": {
+		"start_time": "2026-10-05T09:00:00Z",
+		"end_time": "2026-10-05T11:00:00Z",
+		"duration": "2 hours"
+	}
+}`)
+
+func TestMigrateFromV0(t *testing.T) {
+	data, migrated, err := migrate(v0State)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if !migrated {
+		t.Fatal("v0 state wasn't migrated")
+	}
+
+	var st AppState
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("schema version = %d, want %d", st.SchemaVersion, CurrentSchemaVersion)
+	}
+	if st.CurrentProfile != DefaultProfileName || len(st.Profiles) != 1 || st.Profiles[0].Name != DefaultProfileName {
+		t.Fatalf("profiles = %+v, current %q, want only %q", st.Profiles, st.CurrentProfile, DefaultProfileName)
+	}
+	if want := []string{"youtube.com", "reddit.com"}; !slices.Equal(st.Profiles[0].URLs, want) {
+		t.Errorf("profile URLs = %v, want %v", st.Profiles[0].URLs, want)
+	}
+
+	session := st.ActiveSession
+	if session == nil {
+		t.Fatal("running session was lost")
+	}
+	if !slices.Equal(session.Profiles, []string{DefaultProfileName}) {
+		t.Errorf("session profiles = %v", session.Profiles)
+	}
+	if want := []string{"reddit.com", "youtube.com"}; !slices.Equal(session.URLs, want) {
+		t.Errorf("session URLs = %v, want %v", session.URLs, want)
+	}
+	if want := time.Date(2026, time.October, 5, 11, 0, 0, 0, time.UTC); !session.EndTime.Equal(want) {
+		t.Errorf("session ends at %s, want %s", session.EndTime, want)
+	}
+
+	// The result is current and migrates no further
+	if _, migrated, err := migrate(data); err != nil || migrated {
+		t.Errorf("migrating again: migrated %v, err %v", migrated, err)
+	}
+}
+
+func TestMigrateRefusesNewerVersion(t *testing.T) {
+	data, _ := json.Marshal(map[string]int{"schema_version": CurrentSchemaVersion + 1})
+	if _, _, err := migrate(data); err == nil {
+		t.Error("state of a newer version was accepted")
+	}
+}
+
+func TestImportLegacy(t *testing.T) {
+	useTempState(t)
+
+	imported, err := ImportLegacy(v0State)
+	if err != nil || !imported {
+		t.Fatalf("ImportLegacy = %v, %v", imported, err)
+	}
+
+	st, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !slices.Equal(st.Current().URLs, []string{"reddit.com", "youtube.com"}) {
+		t.Errorf("imported URLs = %v", st.Current().URLs)
+	}
+
+	// An existing state is never replaced
+	if err := Update(func(st *AppState) error { return st.AddURL("example.com") }); err != nil {
+		t.Fatal(err)
+	}
+	imported, err = ImportLegacy(v0State)
+	if err != nil || imported {
+		t.Errorf("ImportLegacy over an existing state = %v, %v", imported, err)
+	}
+	st, _ = Load()
+	if !slices.Contains(st.Current().URLs, "example.com") {
+		t.Error("existing state was replaced")
+	}
+}
@@ -0,0 +1,248 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultProfileName is the profile created for new and migrated states
+const DefaultProfileName = "default"
+
+// Profile is a named list of URLs to block, such as "deep work" or "exam mode"
+type Profile struct {
+	Name string   `json:"name"`
+	URLs []string `json:"urls"`
+
+	// DefaultDuration is preselected when starting a session, e.g. "1h0m0s"
+	DefaultDuration string `json:"default_duration,omitempty"`
+
+	// Mode is ModeAllow for profiles whose URLs are the only ones allowed
+	// during a session; empty means ModeBlock
+	Mode string `json:"mode,omitempty"`
+
+	// Subscriptions are remote lists whose domains are blocked too
+	Subscriptions []Subscription `json:"subscriptions,omitempty"`
+}
+
+// Profile and session modes
+const (
+	ModeBlock = "block"
+	ModeAllow = "allow"
+)
+
+// Allowlist reports whether the profile's URLs are an allowlist
+func (p *Profile) Allowlist() bool {
+	return p.Mode == ModeAllow
+}
+
+// AddURL adds a URL to the profile
+func (p *Profile) AddURL(url string) {
+	// Check for duplicates
+	for _, u := range p.URLs {
+		if u == url {
+			return
+		}
+	}
+	p.URLs = append(p.URLs, url)
+	p.sortURLs()
+}
+
+// AddURLs adds several URLs to the profile, skipping duplicates
+func (p *Profile) AddURLs(urls []string) {
+	seen := make(map[string]bool, len(p.URLs))
+	for _, u := range p.URLs {
+		seen[u] = true
+	}
+	for _, url := range urls {
+		if !seen[url] {
+			seen[url] = true
+			p.URLs = append(p.URLs, url)
+		}
+	}
+	p.sortURLs()
+}
+
+// RemoveURLs removes URLs at the specified indices
+func (p *Profile) RemoveURLs(indices []int) {
+	// Create a map of indices to remove
+	toRemove := make(map[int]bool)
+	for _, idx := range indices {
+		toRemove[idx] = true
+	}
+
+	// Build new slice without removed URLs
+	newURLs := []string{}
+	for i, url := range p.URLs {
+		if !toRemove[i] {
+			newURLs = append(newURLs, url)
+		}
+	}
+	p.URLs = newURLs
+}
+
+// Default returns the profile's default duration, or zero if it has none
+func (p *Profile) Default() time.Duration {
+	d, err := time.ParseDuration(p.DefaultDuration)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// sortURLs sorts the URLs alphabetically
+func (p *Profile) sortURLs() {
+	sort.Slice(p.URLs, func(i, j int) bool {
+		return p.URLs[i] < p.URLs[j]
+	})
+}
+
+// Profile returns the profile with the given name, or nil
+func (s *AppState) Profile(name string) *Profile {
+	for _, p := range s.Profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Current returns the profile being edited in the TUI
+func (s *AppState) Current() *Profile {
+	if p := s.Profile(s.CurrentProfile); p != nil {
+		return p
+	}
+	s.ensureProfile()
+	return s.Profiles[0]
+}
+
+// ProfileURLs returns the union of the URLs of the named profiles
+func (s *AppState) ProfileURLs(names []string) []string {
+	var urls []string
+	for _, name := range names {
+		p := s.Profile(name)
+		if p == nil {
+			continue
+		}
+		for _, url := range p.URLs {
+			if !slices.Contains(urls, url) {
+				urls = append(urls, url)
+			}
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// AddProfile creates an empty profile
+func (s *AppState) AddProfile(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("profile name must not be empty")
+	}
+	if s.Profile(name) != nil {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+
+	s.Profiles = append(s.Profiles, &Profile{Name: name, URLs: []string{}})
+	sort.Slice(s.Profiles, func(i, j int) bool {
+		return s.Profiles[i].Name < s.Profiles[j].Name
+	})
+	return nil
+}
+
+// RemoveProfile deletes a profile that isn't in use
+func (s *AppState) RemoveProfile(name string) error {
+	if s.Profile(name) == nil {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	if len(s.Profiles) == 1 {
+		return fmt.Errorf("cannot remove the last profile")
+	}
+	if s.IsSessionActive() && slices.Contains(s.ActiveSession.Profiles, name) {
+		return fmt.Errorf("profile %q is enforced by the active session", name)
+	}
+
+	s.Profiles = slices.DeleteFunc(s.Profiles, func(p *Profile) bool {
+		return p.Name == name
+	})
+	if s.CurrentProfile == name {
+		s.CurrentProfile = s.Profiles[0].Name
+	}
+	return nil
+}
+
+// SetProfileMode switches a profile between blocking its URLs and allowing
+// only them
+func (s *AppState) SetProfileMode(name, mode string) error {
+	p := s.Profile(name)
+	if p == nil {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	if mode != ModeBlock && mode != ModeAllow {
+		return fmt.Errorf("unknown mode %q, expected %q or %q", mode, ModeBlock, ModeAllow)
+	}
+	if s.IsLocked(name) {
+		return fmt.Errorf("the mode of profile %q can't be changed while a session enforces it", name)
+	}
+	if mode == ModeAllow && len(p.Subscriptions) > 0 {
+		return fmt.Errorf("profile %q follows remote lists; unsubscribe before making it an allowlist", name)
+	}
+
+	p.Mode = mode
+	if mode == ModeBlock {
+		p.Mode = ""
+	}
+	return nil
+}
+
+// ProfilesMode returns the mode shared by the named profiles
+// Sessions can't mix modes, so profiles with different modes are an error.
+func (s *AppState) ProfilesMode(names []string) (string, error) {
+	mode := ""
+	for _, name := range names {
+		p := s.Profile(name)
+		if p == nil {
+			continue
+		}
+		m := ModeBlock
+		if p.Allowlist() {
+			m = ModeAllow
+		}
+		if mode != "" && m != mode {
+			return "", fmt.Errorf("profiles %s mix blocklists and allowlists", strings.Join(names, ", "))
+		}
+		mode = m
+	}
+	if mode == "" {
+		mode = ModeBlock
+	}
+	return mode, nil
+}
+
+// SelectProfile makes the named profile the current one
+func (s *AppState) SelectProfile(name string) error {
+	if s.Profile(name) == nil {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	s.CurrentProfile = name
+	return nil
+}
+
+// ensureProfile makes sure there is at least one profile and that
+// CurrentProfile names an existing one
+func (s *AppState) ensureProfile() {
+	if len(s.Profiles) == 0 {
+		s.Profiles = []*Profile{{Name: DefaultProfileName, URLs: []string{}}}
+	}
+	for _, p := range s.Profiles {
+		if p.URLs == nil {
+			p.URLs = []string{}
+		}
+	}
+	if s.Profile(s.CurrentProfile) == nil {
+		s.CurrentProfile = s.Profiles[0].Name
+	}
+}
@@ -1,17 +1,61 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
+	"slices"
+	"syscall"
 	"time"
+
+	"github.com/phil/selfcontrol/internal/clock"
+	"github.com/phil/selfcontrol/internal/fsutil"
 )
 
 // AppState represents the persistent application state
 type AppState struct {
-	URLs          []string   `json:"urls"`
-	ActiveSession *Session   `json:"active_session,omitempty"`
+	// SchemaVersion is the layout version of the state file, see migrate.go
+	SchemaVersion int `json:"schema_version"`
+
+	// Profiles are the named block lists; CurrentProfile is the one being
+	// edited and started from the TUI
+	Profiles       []*Profile `json:"profiles"`
+	CurrentProfile string     `json:"current_profile"`
+
+	ActiveSession *Session `json:"active_session,omitempty"`
+
+	// Backend selects the blocking backend (see blocker.New); empty means hosts
+	Backend string `json:"backend,omitempty"`
+
+	// DNS configures the daemon's optional local DNS sinkhole
+	DNS *DNSConfig `json:"dns,omitempty"`
+
+	// Events lists the most recent noteworthy events, oldest first
+	Events []Event `json:"events,omitempty"`
+
+	// Schedules start sessions automatically, see the schedule package
+	Schedules []Schedule `json:"schedules,omitempty"`
+
+	// ScheduledUntil is the end of the last scheduled window a session was
+	// started for, so a window isn't started again after ending early
+	ScheduledUntil time.Time `json:"scheduled_until,omitempty"`
+
+	// UnlockDelay is the cooling-off period of an emergency unlock, e.g.
+	// "15m"; DefaultUnlockDelay if empty
+	UnlockDelay string `json:"unlock_delay,omitempty"`
+
+	// history holds session history lines written on the next save
+	history []HistoryEntry
+}
+
+// DNSConfig configures the local DNS sinkhole run by the daemon
+type DNSConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Listen   string `json:"listen,omitempty"`
+	Upstream string `json:"upstream,omitempty"`
 }
 
 // Session represents an active blocking session
@@ -19,6 +63,124 @@ type Session struct {
 	EndTime   time.Time `json:"end_time"`
 	Duration  string    `json:"duration"`
 	StartTime time.Time `json:"start_time"`
+
+	// Profiles lists the profiles whose URLs the session enforces
+	Profiles []string `json:"profiles"`
+
+	// URLs are the URLs the session enforces: its profiles' URLs when it
+	// started, plus any added to them since
+	URLs []string `json:"urls"`
+
+	// Subscriptions are the remote lists the session enforces
+	Subscriptions []string `json:"subscriptions,omitempty"`
+
+	// SubscribedURLs are the domains of those lists, copied when the session
+	// starts and whenever a list is fetched; like URLs, they only grow, so a
+	// list that shrinks mid-session doesn't lift blocks. They are kept apart
+	// from URLs to keep them out of the history.
+	SubscribedURLs []string `json:"subscribed_urls,omitempty"`
+
+	// Scheduled is set for sessions started by a schedule
+	Scheduled bool `json:"scheduled,omitempty"`
+
+	// Location is the IANA time zone the end time was chosen in, so it is
+	// shown the same way after DST changes or travel; empty means local
+	Location string `json:"location,omitempty"`
+
+	// TamperCount counts how often the blocking rules had to be re-applied
+	TamperCount int `json:"tamper_count,omitempty"`
+
+	// Checkpoint is a clock reading from the current boot; the time elapsed
+	// since then on the boot clock tells whether the wall clock was changed
+	Checkpoint *clock.Reading `json:"checkpoint,omitempty"`
+
+	// ClockSkew is how far the wall clock was last found to be off
+	ClockSkew time.Duration `json:"clock_skew,omitempty"`
+
+	// Unlock is a pending emergency unlock
+	Unlock *Unlock `json:"unlock,omitempty"`
+
+	// Mode is ModeAllow when everything except URLs is blocked; empty
+	// means ModeBlock
+	Mode string `json:"mode,omitempty"`
+}
+
+// Allowlist reports whether the session blocks everything except its URLs
+func (s *Session) Allowlist() bool {
+	return s.Mode == ModeAllow
+}
+
+// ClockTolerance is how far the wall clock may drift from the boot clock
+// before a session stops trusting it
+const ClockTolerance = 2 * time.Minute
+
+// Now returns the current time as far as the session can trust it, and how
+// far the wall clock is off
+// This is the wall clock unless it disagrees with the time elapsed on the
+// boot clock since the checkpoint by more than ClockTolerance, so changing
+// the system clock can't end a session early.
+func (s *Session) Now(r clock.Reading) (time.Time, time.Duration) {
+	if s.Checkpoint == nil {
+		return r.Wall, 0
+	}
+	wall, boot, ok := clock.Elapsed(*s.Checkpoint, r)
+	if !ok {
+		return r.Wall, 0
+	}
+
+	skew := wall - boot
+	if skew.Abs() <= ClockTolerance {
+		return r.Wall, skew
+	}
+	return s.Checkpoint.Wall.Add(boot), skew
+}
+
+// Remaining returns the time left in the session at r
+func (s *Session) Remaining(r clock.Reading) time.Duration {
+	now, _ := s.Now(r)
+	return s.EndTime.Sub(now)
+}
+
+// Zone returns the session's time zone, or the local one if it is unknown
+func (s *Session) Zone() *time.Location {
+	if s.Location == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Location)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Schedule blocks a profile on recurring days and times
+type Schedule struct {
+	// Days are weekday names ("mon", "tuesday", ...) or "weekdays",
+	// "weekends" and "daily"
+	Days []string `json:"days"`
+
+	// Start and End are local wall-clock times like "09:00"; an End before
+	// Start means the window ends the next day
+	Start string `json:"start"`
+	End   string `json:"end"`
+
+	Profile string `json:"profile"`
+}
+
+// Event kinds
+const (
+	EventTamper = "tamper"
+	EventUnlock = "unlock"
+)
+
+// maxEvents bounds how many events are kept in the state file
+const maxEvents = 100
+
+// Event records something noteworthy that happened, such as tampering
+type Event struct {
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail,omitempty"`
 }
 
 var statePath string
@@ -32,11 +194,20 @@ func init() {
 // Uses /var/lib/selfcontrol as a persistent location accessible by both TUI and daemon
 func determineStatePath() string {
 	// Use /var/lib/selfcontrol for persistent storage across reboots
-	// This ensures both TUI (run with sudo) and daemon access the same state file
-	// URLs and active sessions persist across reboots
+	// The daemon owns this file; clients read it and change it through the
+	// control socket, or directly when run as root without the daemon
 	return "/var/lib/selfcontrol/state.json"
 }
 
+// SetDir keeps the state file, the session history and the subscription
+// cache in dir instead of /var/lib/selfcontrol, for tests of packages that
+// use the state
+func SetDir(dir string) {
+	statePath = filepath.Join(dir, "state.json")
+	historyPath = filepath.Join(dir, "history.jsonl")
+	SubscriptionDir = filepath.Join(dir, "subscriptions")
+}
+
 // GetStatePath returns the current state file path (useful for debugging)
 func GetStatePath() string {
 	return statePath
@@ -44,109 +215,334 @@ func GetStatePath() string {
 
 // Load reads the state from disk
 func Load() (*AppState, error) {
-	// Ensure config directory exists
-	dir := filepath.Dir(statePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	unlock, err := lockState(syscall.LOCK_SH)
+	if err != nil {
 		return nil, err
 	}
+	state, migrated, err := load()
+	unlock()
+	if err != nil || !migrated {
+		return state, err
+	}
 
-	// Check if state file exists
-	if _, err := os.Stat(statePath); os.IsNotExist(err) {
-		// Return empty state
-		return &AppState{
-			URLs: []string{},
-		}, nil
+	// Persist the migrated state; readers that can't write simply use the
+	// migrated copy in memory
+	Update(func(*AppState) error { return nil })
+
+	return state, nil
+}
+
+// Update loads the state, applies fn and saves the result while holding an
+// exclusive lock, so no other process can change the state in between
+// Nothing is written if fn returns an error or leaves the state unchanged.
+func Update(fn func(*AppState) error) error {
+	unlock, err := lockState(syscall.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, migrated, err := load()
+	if err != nil {
+		return err
+	}
+	before, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(state); err != nil {
+		return err
+	}
+
+	after, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if !migrated && bytes.Equal(before, after) {
+		return nil
 	}
 
+	if err := save(state); err != nil {
+		return err
+	}
+	return flushHistory(state)
+}
+
+// load reads the state file, migrating it to the current schema version;
+// the caller must hold the lock
+// migrated reports whether the result differs from what is on disk.
+func load() (state *AppState, migrated bool, err error) {
 	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		// Return empty state
+		state = &AppState{SchemaVersion: CurrentSchemaVersion}
+		state.ensureProfile()
+		return state, false, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	var state AppState
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, err
+	state, migrated, err = parse(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return state, migrated, nil
+}
+
+// parse decodes a state file of any schema version
+// migrated reports whether it had to be migrated.
+func parse(data []byte) (state *AppState, migrated bool, err error) {
+	data, migrated, err = migrate(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	state = &AppState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, false, err
 	}
 
+	state.ensureProfile()
+
 	// Sort URLs alphabetically
-	state.sortURLs()
+	for _, p := range state.Profiles {
+		p.sortURLs()
+	}
 
-	return &state, nil
+	return state, migrated, nil
 }
 
-// Save writes the state to disk
-func Save(state *AppState) error {
+// save writes the state file; the caller must hold the exclusive lock
+func save(state *AppState) error {
+	state.SchemaVersion = CurrentSchemaVersion
+
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(statePath, data, 0644)
+	return fsutil.WriteFileAtomic(statePath, data, 0644)
+}
+
+// flushHistory appends the history lines collected since the state was
+// loaded; the caller must hold the exclusive lock
+func flushHistory(state *AppState) error {
+	if err := appendHistory(state.history); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	state.history = nil
+	return nil
+}
+
+// AddURL adds a URL to the current profile, and to the active session if it
+// enforces the profile
+// It fails with ErrAllowlistLocked if that session is an allowlist session.
+func (s *AppState) AddURL(url string) error {
+	if !s.IsLocked(s.CurrentProfile) {
+		s.Current().AddURL(url)
+		return nil
+	}
+	if s.ActiveSession.Allowlist() {
+		return ErrAllowlistLocked
+	}
+	s.Current().AddURL(url)
+	s.ActiveSession.addURLs([]string{url})
+	return nil
+}
+
+// AddURLs adds several URLs like AddURL
+func (s *AppState) AddURLs(urls []string) error {
+	if !s.IsLocked(s.CurrentProfile) {
+		s.Current().AddURLs(urls)
+		return nil
+	}
+	if s.ActiveSession.Allowlist() {
+		return ErrAllowlistLocked
+	}
+	s.Current().AddURLs(urls)
+	s.ActiveSession.addURLs(urls)
+	return nil
+}
+
+// AddSessionProfiles makes the active session enforce more profiles too
+func (s *AppState) AddSessionProfiles(names []string) {
+	for _, name := range names {
+		if !slices.Contains(s.ActiveSession.Profiles, name) {
+			s.ActiveSession.Profiles = append(s.ActiveSession.Profiles, name)
+		}
+	}
+	s.ActiveSession.addURLs(s.ProfileURLs(names))
+	s.ActiveSession.addSubscriptions(s.ProfileSubscriptions(names))
 }
 
-// AddURL adds a URL to the state
-func (s *AppState) AddURL(url string) {
-	// Check for duplicates
-	for _, u := range s.URLs {
-		if u == url {
-			return
+// addURLs adds URLs to those enforced by the session
+func (s *Session) addURLs(urls []string) {
+	seen := make(map[string]bool, len(s.URLs))
+	for _, url := range s.URLs {
+		seen[url] = true
+	}
+	for _, url := range urls {
+		if !seen[url] {
+			seen[url] = true
+			s.URLs = append(s.URLs, url)
 		}
 	}
-	s.URLs = append(s.URLs, url)
-	s.sortURLs()
+	slices.Sort(s.URLs)
 }
 
-// sortURLs sorts the URLs alphabetically (case-insensitive)
-func (s *AppState) sortURLs() {
-	sort.Slice(s.URLs, func(i, j int) bool {
-		return s.URLs[i] < s.URLs[j]
+// removeSessionURLs stops allowing URLs that none of the session's profiles list
+func (s *AppState) removeSessionURLs(urls []string) {
+	enforced := s.ProfileURLs(s.ActiveSession.Profiles)
+	s.ActiveSession.URLs = slices.DeleteFunc(s.ActiveSession.URLs, func(url string) bool {
+		return slices.Contains(urls, url) && !slices.Contains(enforced, url)
 	})
 }
 
-// RemoveURLs removes URLs at the specified indices
-func (s *AppState) RemoveURLs(indices []int) {
-	// Create a map of indices to remove
-	toRemove := make(map[int]bool)
-	for _, idx := range indices {
-		toRemove[idx] = true
+// ErrSessionLocked is returned when removing URLs that the active session
+// enforces
+var ErrSessionLocked = errors.New("URLs can't be removed while a session enforces this profile; adding is still allowed")
+
+// ErrAllowlistLocked is returned when adding URLs to an allowlist that the
+// active session enforces
+var ErrAllowlistLocked = errors.New("URLs can't be added while an allowlist session enforces this profile; removing is still allowed")
+
+// RemoveURLs removes URLs of the current profile at the specified indices
+// It fails with ErrSessionLocked if the active session enforces the profile,
+// unless it is an allowlist session, which stops allowing them right away.
+func (s *AppState) RemoveURLs(indices []int) error {
+	if !s.IsLocked(s.CurrentProfile) {
+		s.Current().RemoveURLs(indices)
+		return nil
+	}
+	if !s.ActiveSession.Allowlist() {
+		return ErrSessionLocked
 	}
 
-	// Build new slice without removed URLs
-	newURLs := []string{}
-	for i, url := range s.URLs {
-		if !toRemove[i] {
-			newURLs = append(newURLs, url)
+	var removed []string
+	for _, i := range indices {
+		if i >= 0 && i < len(s.Current().URLs) {
+			removed = append(removed, s.Current().URLs[i])
 		}
 	}
-	s.URLs = newURLs
+	s.Current().RemoveURLs(indices)
+	s.removeSessionURLs(removed)
+	return nil
+}
+
+// IsLocked reports whether the active session enforces the named profile,
+// so its list can only be made stricter
+func (s *AppState) IsLocked(profile string) bool {
+	return s.IsSessionActive() && slices.Contains(s.ActiveSession.Profiles, profile)
+}
+
+// StartSession starts a new blocking session enforcing the given profiles,
+// or the current profile if none are given
+func (s *AppState) StartSession(duration time.Duration, durationStr string, profiles []string) {
+	s.StartSessionUntil(time.Now().Add(duration), durationStr, profiles)
 }
 
-// StartSession starts a new blocking session
-func (s *AppState) StartSession(duration time.Duration, durationStr string) {
+// StartSessionUntil starts a new blocking session ending at end, recording
+// end's location as the session's time zone
+// A previous session that is over but wasn't ended yet is ended first, so
+// it isn't lost from the history.
+func (s *AppState) StartSessionUntil(end time.Time, durationStr string, profiles []string) {
+	if reason, over := s.SessionOver(); over {
+		if reason == EndCancelled {
+			s.RecordEvent(EventUnlock, "session ended by emergency unlock")
+		}
+		s.EndSession(reason)
+	}
+
+	if len(profiles) == 0 {
+		profiles = []string{s.CurrentProfile}
+	}
+
+	now := clock.Read()
 	s.ActiveSession = &Session{
-		StartTime: time.Now(),
-		EndTime:   time.Now().Add(duration),
+		StartTime: now.Wall,
+		EndTime:   end,
 		Duration:  durationStr,
+		Profiles:  profiles,
+		URLs:      s.ProfileURLs(profiles),
 	}
+	s.ActiveSession.addSubscriptions(s.ProfileSubscriptions(profiles))
+	if mode, _ := s.ProfilesMode(profiles); mode == ModeAllow {
+		s.ActiveSession.Mode = ModeAllow
+	}
+	if now.BootID != "" {
+		s.ActiveSession.Checkpoint = &now
+	}
+	if loc := end.Location(); loc != time.Local {
+		s.ActiveSession.Location = loc.String()
+	}
+	s.history = append(s.history, s.historyEntry())
 }
 
-// EndSession ends the current blocking session
-func (s *AppState) EndSession() {
+// SessionURLs returns the URLs enforced by the active session, including
+// the domains of its subscribed lists
+func (s *AppState) SessionURLs() []string {
+	if s.ActiveSession == nil {
+		return nil
+	}
+	if len(s.ActiveSession.SubscribedURLs) == 0 {
+		return s.ActiveSession.URLs
+	}
+
+	seen := make(map[string]bool, len(s.ActiveSession.URLs))
+	urls := slices.Clone(s.ActiveSession.URLs)
+	for _, url := range urls {
+		seen[url] = true
+	}
+	for _, url := range s.ActiveSession.SubscribedURLs {
+		if !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// EndSession ends the current blocking session, recording why in the
+// history (EndExpired, EndCancelled or EndTampered)
+func (s *AppState) EndSession(reason string) {
+	if s.ActiveSession == nil {
+		return
+	}
+
+	entry := s.historyEntry()
+	entry.ActualEnd = time.Now()
+	entry.EndReason = reason
+	s.history = append(s.history, entry)
+
 	s.ActiveSession = nil
 }
 
+// RecordEvent appends an event, dropping the oldest ones beyond the limit
+func (s *AppState) RecordEvent(kind, detail string) {
+	s.Events = append(s.Events, Event{
+		Time:   time.Now(),
+		Kind:   kind,
+		Detail: detail,
+	})
+	if len(s.Events) > maxEvents {
+		s.Events = s.Events[len(s.Events)-maxEvents:]
+	}
+}
+
 // IsSessionActive returns true if there is an active session
 func (s *AppState) IsSessionActive() bool {
 	if s.ActiveSession == nil {
 		return false
 	}
-	return time.Now().Before(s.ActiveSession.EndTime)
+	return s.ActiveSession.Remaining(clock.Read()) > 0
 }
 
 // TimeRemaining returns the time remaining in the current session
 func (s *AppState) TimeRemaining() time.Duration {
-	if !s.IsSessionActive() {
+	if s.ActiveSession == nil {
 		return 0
 	}
-	return time.Until(s.ActiveSession.EndTime)
+	return max(s.ActiveSession.Remaining(clock.Read()), 0)
 }
@@ -0,0 +1,235 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/clock"
+)
+
+// useTempState points the state and history files at a temporary directory
+func useTempState(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	oldState, oldHistory := statePath, historyPath
+	statePath = filepath.Join(dir, "state.json")
+	historyPath = filepath.Join(dir, "history.jsonl")
+	t.Cleanup(func() {
+		statePath, historyPath = oldState, oldHistory
+	})
+
+	// Keep a legacy state file of the user running the tests out
+	t.Setenv("HOME", dir)
+	t.Setenv("SUDO_USER", "")
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	useTempState(t)
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Update(func(st *AppState) error {
+				return st.AddURL(fmt.Sprintf("site%d.example", i))
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+
+	st, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	urls := st.Current().URLs
+	if len(urls) != n {
+		t.Fatalf("got %d URLs, want %d: %v", len(urls), n, urls)
+	}
+	for i := 0; i < n; i++ {
+		if !slices.Contains(urls, fmt.Sprintf("site%d.example", i)) {
+			t.Errorf("site%d.example was lost", i)
+		}
+	}
+}
+
+func TestUpdateUnchangedDoesNotWrite(t *testing.T) {
+	useTempState(t)
+
+	if err := Update(func(st *AppState) error { return nil }); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("unchanged state was written to %s", statePath)
+	}
+}
+
+func TestStartSessionEndsExpiredSession(t *testing.T) {
+	useTempState(t)
+
+	expired := time.Now().Add(-time.Minute)
+	if err := Update(func(st *AppState) error {
+		st.StartSessionUntil(expired, "expired", nil)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Update(func(st *AppState) error {
+		st.StartSession(time.Hour, "1 hour", nil)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := LoadHistory(st.ActiveSession)
+	if err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2: %+v", len(sessions), sessions)
+	}
+	if first := sessions[0]; first.EndReason != EndExpired || first.ActualEnd.IsZero() || !first.PlannedEnd.Equal(expired) {
+		t.Errorf("expired session recorded as %+v", first)
+	}
+	if second := sessions[1]; !second.ActualEnd.IsZero() {
+		t.Errorf("new session recorded as ended: %+v", second)
+	}
+}
+
+func TestSessionClock(t *testing.T) {
+	start := time.Date(2026, time.October, 5, 9, 0, 0, 0, time.UTC)
+	checkpoint := clock.Reading{Wall: start, Uptime: 100 * time.Second, BootID: "boot-a"}
+
+	// reading is taken after elapsed time since the checkpoint, with the
+	// wall clock off by skew
+	reading := func(elapsed, skew time.Duration) clock.Reading {
+		return clock.Reading{Wall: start.Add(elapsed + skew), Uptime: checkpoint.Uptime + elapsed, BootID: "boot-a"}
+	}
+
+	tests := []struct {
+		name      string
+		r         clock.Reading
+		now       time.Time
+		skew      time.Duration
+		remaining time.Duration
+	}{
+		{"in sync", reading(10*time.Minute, 0), start.Add(10 * time.Minute), 0, 50 * time.Minute},
+		{"within tolerance", reading(10*time.Minute, time.Minute), start.Add(11 * time.Minute), time.Minute, 49 * time.Minute},
+		{"jumped forward", reading(10*time.Minute, 2*time.Hour), start.Add(10 * time.Minute), 2 * time.Hour, 50 * time.Minute},
+		{"jumped backward", reading(10*time.Minute, -time.Hour), start.Add(10 * time.Minute), -time.Hour, 50 * time.Minute},
+		{"after reboot", clock.Reading{Wall: start.Add(3 * time.Hour), Uptime: 5 * time.Second, BootID: "boot-b"}, start.Add(3 * time.Hour), 0, -2 * time.Hour},
+		{"no boot clock", clock.Reading{Wall: start.Add(30 * time.Minute)}, start.Add(30 * time.Minute), 0, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := checkpoint
+			session := &Session{StartTime: start, EndTime: start.Add(time.Hour), Checkpoint: &cp}
+
+			now, skew := session.Now(tt.r)
+			if !now.Equal(tt.now) || skew != tt.skew {
+				t.Errorf("Now = %s, skew %s; want %s, skew %s", now, skew, tt.now, tt.skew)
+			}
+			if got := session.Remaining(tt.r); got != tt.remaining {
+				t.Errorf("Remaining = %s, want %s", got, tt.remaining)
+			}
+		})
+	}
+
+	// Without a checkpoint the wall clock is all there is
+	session := &Session{EndTime: start.Add(time.Hour)}
+	if got := session.Remaining(reading(0, 2*time.Hour)); got != -time.Hour {
+		t.Errorf("Remaining without checkpoint = %s, want -1h", got)
+	}
+}
+
+func TestSessionLocksEnforcedProfile(t *testing.T) {
+	useTempState(t)
+
+	if err := Update(func(st *AppState) error {
+		if err := st.AddURLs([]string{"reddit.com", "youtube.com"}); err != nil {
+			return err
+		}
+		if err := st.AddProfile("news"); err != nil {
+			return err
+		}
+		if err := st.SelectProfile("news"); err != nil {
+			return err
+		}
+		if err := st.AddURL("news.ycombinator.com"); err != nil {
+			return err
+		}
+		if err := st.SelectProfile(DefaultProfileName); err != nil {
+			return err
+		}
+		st.StartSession(time.Hour, "1 hour", nil)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// URLs of the enforced profile can't be removed
+	err := Update(func(st *AppState) error {
+		if !st.IsLocked(DefaultProfileName) || st.IsLocked("news") {
+			t.Errorf("locked: default %v, news %v; want true, false", st.IsLocked(DefaultProfileName), st.IsLocked("news"))
+		}
+		return st.RemoveURLs([]int{0})
+	})
+	if !errors.Is(err, ErrSessionLocked) {
+		t.Fatalf("RemoveURLs = %v, want ErrSessionLocked", err)
+	}
+
+	// Added ones are enforced right away, and kept with the session
+	if err := Update(func(st *AppState) error { return st.AddURL("twitter.com") }); err != nil {
+		t.Fatalf("AddURL: %v", err)
+	}
+
+	st, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"reddit.com", "twitter.com", "youtube.com"}
+	if !slices.Equal(st.Current().URLs, want) {
+		t.Errorf("profile URLs = %v, want %v", st.Current().URLs, want)
+	}
+	got := slices.Clone(st.ActiveSession.URLs)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("session URLs = %v, want %v", st.ActiveSession.URLs, want)
+	}
+
+	// Profiles the session doesn't enforce can still be edited
+	if err := Update(func(st *AppState) error {
+		if err := st.SelectProfile("news"); err != nil {
+			return err
+		}
+		return st.RemoveURLs([]int{0})
+	}); err != nil {
+		t.Fatalf("RemoveURLs on an unenforced profile: %v", err)
+	}
+	st, _ = Load()
+	if len(st.Current().URLs) != 0 {
+		t.Errorf("news URLs = %v, want none", st.Current().URLs)
+	}
+	if slices.Contains(st.SessionURLs(), "news.ycombinator.com") {
+		t.Error("unenforced profile leaked into the session")
+	}
+}
@@ -0,0 +1,288 @@
+package state
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/fsutil"
+)
+
+// SubscriptionDir caches the domains of subscribed blocklists, so they keep
+// being blocked while the lists can't be fetched; tests point it at a
+// temporary directory
+var SubscriptionDir = "/var/lib/selfcontrol/subscriptions"
+
+// Subscription refresh intervals
+const (
+	DefaultRefresh = 24 * time.Hour
+	MinRefresh     = time.Hour
+)
+
+// Subscription is a remote blocklist that a profile follows; the daemon
+// fetches it regularly and blocks its domains along with the profile's URLs
+type Subscription struct {
+	URL string `json:"url"`
+
+	// Refresh is how often the list is fetched, e.g. "12h"; DefaultRefresh
+	// if empty
+	Refresh string `json:"refresh,omitempty"`
+}
+
+// Interval returns how often the list is fetched
+func (s Subscription) Interval() time.Duration {
+	d, err := time.ParseDuration(s.Refresh)
+	if err != nil || d < MinRefresh {
+		return DefaultRefresh
+	}
+	return d
+}
+
+// SubscriptionCache describes the cached copy of a subscribed list
+type SubscriptionCache struct {
+	URL string `json:"url"`
+
+	// ETag and LastModified are the validators of the cached copy, sent
+	// with the next request so unchanged lists aren't downloaded again
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	// FetchedAt is when the list was last fetched or confirmed unchanged,
+	// CheckedAt when that was last attempted
+	FetchedAt time.Time `json:"fetched_at,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+
+	// Error is why the last attempt failed, empty if it succeeded
+	Error string `json:"error,omitempty"`
+
+	// Domains is the number of cached domains
+	Domains int `json:"domains"`
+}
+
+// SubscriptionStatus is a subscription of a profile with its cache
+type SubscriptionStatus struct {
+	Profile      string
+	Subscription Subscription
+
+	// Cache is nil if the list wasn't fetched yet
+	Cache *SubscriptionCache
+}
+
+// subscriptionPath returns the cache file for a list URL with the extension
+func subscriptionPath(listURL, ext string) string {
+	sum := sha256.Sum256([]byte(listURL))
+	return filepath.Join(SubscriptionDir, hex.EncodeToString(sum[:8])+ext)
+}
+
+// LoadSubscriptionCache returns the cache of a list, or nil if there is none
+func LoadSubscriptionCache(listURL string) (*SubscriptionCache, error) {
+	data, err := os.ReadFile(subscriptionPath(listURL, ".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscription cache: %w", err)
+	}
+
+	var cache SubscriptionCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse subscription cache: %w", err)
+	}
+	return &cache, nil
+}
+
+// SubscriptionDomains returns the cached domains of a list
+func SubscriptionDomains(listURL string) ([]string, error) {
+	f, err := os.Open(subscriptionPath(listURL, ".list"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscription cache: %w", err)
+	}
+	defer f.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			domains = append(domains, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read subscription cache: %w", err)
+	}
+	return domains, nil
+}
+
+// SaveSubscriptionCache writes the cache of a list, replacing its domains
+// unless domains is nil
+func SaveSubscriptionCache(cache *SubscriptionCache, domains []string) error {
+	if err := os.MkdirAll(SubscriptionDir, 0755); err != nil {
+		return fmt.Errorf("failed to create subscription cache: %w", err)
+	}
+
+	// Domains first, so the description never counts domains that aren't
+	// there yet
+	if domains != nil {
+		data := strings.Join(domains, "\n") + "\n"
+		if err := fsutil.WriteFileAtomic(subscriptionPath(cache.URL, ".list"), []byte(data), 0644); err != nil {
+			return fmt.Errorf("failed to write subscription cache: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(subscriptionPath(cache.URL, ".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write subscription cache: %w", err)
+	}
+	return nil
+}
+
+// Subscribe makes a blocklist profile follow a remote list, fetched every
+// refresh (DefaultRefresh if zero)
+func (s *AppState) Subscribe(profile, listURL string, refresh time.Duration) error {
+	p := s.Profile(profile)
+	if p == nil {
+		return fmt.Errorf("no profile named %q", profile)
+	}
+	if p.Allowlist() {
+		return fmt.Errorf("profile %q is an allowlist; subscriptions are only supported for blocklists", profile)
+	}
+	u, err := url.Parse(listURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid list URL %q, expected an http or https URL", listURL)
+	}
+	if refresh != 0 && refresh < MinRefresh {
+		return fmt.Errorf("refresh interval must be at least %s", MinRefresh)
+	}
+
+	sub := Subscription{URL: listURL}
+	if refresh != 0 && refresh != DefaultRefresh {
+		sub.Refresh = refresh.String()
+	}
+	if i := slices.IndexFunc(p.Subscriptions, func(s Subscription) bool { return s.URL == listURL }); i >= 0 {
+		p.Subscriptions[i] = sub
+	} else {
+		p.Subscriptions = append(p.Subscriptions, sub)
+	}
+
+	if s.IsLocked(profile) {
+		s.ActiveSession.addSubscriptions([]string{listURL})
+	}
+	return nil
+}
+
+// Unsubscribe stops a profile from following a remote list
+// Like removing URLs, it fails with ErrSessionLocked while the active
+// session enforces the profile.
+func (s *AppState) Unsubscribe(profile, listURL string) error {
+	p := s.Profile(profile)
+	if p == nil {
+		return fmt.Errorf("no profile named %q", profile)
+	}
+	i := slices.IndexFunc(p.Subscriptions, func(s Subscription) bool { return s.URL == listURL })
+	if i < 0 {
+		return fmt.Errorf("profile %q isn't subscribed to %s", profile, listURL)
+	}
+	if s.IsLocked(profile) {
+		return ErrSessionLocked
+	}
+
+	p.Subscriptions = slices.Delete(p.Subscriptions, i, i+1)
+	return nil
+}
+
+// SubscriptionStatuses returns every subscription of every profile with its
+// cache
+func (s *AppState) SubscriptionStatuses() ([]SubscriptionStatus, error) {
+	var statuses []SubscriptionStatus
+	for _, p := range s.Profiles {
+		for _, sub := range p.Subscriptions {
+			cache, err := LoadSubscriptionCache(sub.URL)
+			if err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, SubscriptionStatus{Profile: p.Name, Subscription: sub, Cache: cache})
+		}
+	}
+	return statuses, nil
+}
+
+// ProfileSubscriptions returns the URLs of the lists the named profiles
+// follow
+func (s *AppState) ProfileSubscriptions(names []string) []string {
+	var urls []string
+	for _, name := range names {
+		p := s.Profile(name)
+		if p == nil {
+			continue
+		}
+		for _, sub := range p.Subscriptions {
+			if !slices.Contains(urls, sub.URL) {
+				urls = append(urls, sub.URL)
+			}
+		}
+	}
+	return urls
+}
+
+// MergeSubscription adds the cached domains of a list to the active
+// session if it enforces the list, and reports whether any were new
+// Domains are never removed, so the session can't be shortened by editing
+// the remote list.
+func (s *AppState) MergeSubscription(listURL string) (bool, error) {
+	if !s.IsSessionActive() || !slices.Contains(s.ActiveSession.Subscriptions, listURL) {
+		return false, nil
+	}
+	domains, err := SubscriptionDomains(listURL)
+	if err != nil {
+		return false, err
+	}
+	return s.ActiveSession.addSubscribedURLs(domains), nil
+}
+
+// addSubscriptions adds lists to those enforced by the session, along with
+// their cached domains
+// A list that can't be read yet is added without domains; they are merged
+// once the daemon fetched it.
+func (s *Session) addSubscriptions(urls []string) {
+	for _, url := range urls {
+		if !slices.Contains(s.Subscriptions, url) {
+			s.Subscriptions = append(s.Subscriptions, url)
+		}
+		domains, _ := SubscriptionDomains(url)
+		s.addSubscribedURLs(domains)
+	}
+}
+
+// addSubscribedURLs adds domains of subscribed lists to the session and
+// reports whether any were new
+func (s *Session) addSubscribedURLs(domains []string) bool {
+	seen := make(map[string]bool, len(s.SubscribedURLs))
+	for _, domain := range s.SubscribedURLs {
+		seen[domain] = true
+	}
+	added := false
+	for _, domain := range domains {
+		if !seen[domain] {
+			seen[domain] = true
+			s.SubscribedURLs = append(s.SubscribedURLs, domain)
+			added = true
+		}
+	}
+	if added {
+		slices.Sort(s.SubscribedURLs)
+	}
+	return added
+}
@@ -0,0 +1,50 @@
+package state
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMergeSubscriptionOnlyAdds(t *testing.T) {
+	useTempState(t)
+	old := SubscriptionDir
+	SubscriptionDir = t.TempDir()
+	t.Cleanup(func() { SubscriptionDir = old })
+
+	const listURL = "https://lists.example/hosts"
+	if err := SaveSubscriptionCache(&SubscriptionCache{URL: listURL, Domains: 2}, []string{"a.example", "b.example"}); err != nil {
+		t.Fatal(err)
+	}
+
+	st := &AppState{}
+	st.ensureProfile()
+	st.Current().AddURL("own.example")
+	if err := st.Subscribe(DefaultProfileName, listURL, 0); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	st.StartSession(time.Hour, "1 hour", nil)
+
+	want := []string{"own.example", "a.example", "b.example"}
+	if got := st.SessionURLs(); !slices.Equal(got, want) {
+		t.Fatalf("SessionURLs = %v, want %v", got, want)
+	}
+
+	// The list shrinks and gains a domain mid-session
+	if err := SaveSubscriptionCache(&SubscriptionCache{URL: listURL, Domains: 1}, []string{"c.example"}); err != nil {
+		t.Fatal(err)
+	}
+	added, err := st.MergeSubscription(listURL)
+	if err != nil || !added {
+		t.Fatalf("MergeSubscription = %v, %v; want true, nil", added, err)
+	}
+	want = []string{"own.example", "a.example", "b.example", "c.example"}
+	if got := st.SessionURLs(); !slices.Equal(got, want) {
+		t.Errorf("SessionURLs = %v, want %v", got, want)
+	}
+
+	// Unsubscribing is refused while the session enforces the profile
+	if err := st.Unsubscribe(DefaultProfileName, listURL); err != ErrSessionLocked {
+		t.Errorf("Unsubscribe = %v, want ErrSessionLocked", err)
+	}
+}
@@ -0,0 +1,165 @@
+package state
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/clock"
+)
+
+// DefaultUnlockDelay is the cooling-off period of an emergency unlock
+const DefaultUnlockDelay = 15 * time.Minute
+
+// Emergency unlock challenges are long random strings without characters
+// that are easily confused
+const (
+	challengeLength   = 48
+	challengeAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// ErrWrongChallenge is returned when the typed challenge doesn't match
+var ErrWrongChallenge = errors.New("the typed text doesn't match the challenge")
+
+// Unlock is an emergency unlock of the active session
+// It is requested with a challenge, confirmed by typing the challenge, and
+// takes effect once the delay has passed.
+type Unlock struct {
+	// ChallengeHash is the SHA-256 of the challenge; the state file can be
+	// read by anyone, so the challenge itself is only handed to the caller
+	// of RequestUnlock
+	ChallengeHash string    `json:"challenge_hash"`
+	RequestedAt   time.Time `json:"requested_at"`
+
+	// UnlockAt is set once the challenge was typed; it is measured on the
+	// session's clock, see Session.Now
+	UnlockAt time.Time `json:"unlock_at,omitempty"`
+}
+
+// Confirmed reports whether the challenge was typed
+func (u *Unlock) Confirmed() bool {
+	return !u.UnlockAt.IsZero()
+}
+
+// GetUnlockDelay returns the configured emergency unlock delay
+func (s *AppState) GetUnlockDelay() time.Duration {
+	d, err := time.ParseDuration(s.UnlockDelay)
+	if err != nil || d < 0 {
+		return DefaultUnlockDelay
+	}
+	return d
+}
+
+// RequestUnlock starts an emergency unlock of the active session and
+// returns the challenge to type
+// Only a hash of the challenge is kept, so requesting again before the
+// challenge was typed replaces it with a new one.
+func (s *AppState) RequestUnlock() (string, error) {
+	if !s.IsSessionActive() {
+		return "", fmt.Errorf("no active session")
+	}
+	if u := s.ActiveSession.Unlock; u != nil && u.Confirmed() {
+		return "", fmt.Errorf("an emergency unlock is already confirmed")
+	}
+
+	challenge, err := newChallenge()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	s.ActiveSession.Unlock = &Unlock{ChallengeHash: hashChallenge(challenge), RequestedAt: time.Now()}
+	return challenge, nil
+}
+
+// ConfirmUnlock checks the typed challenge and schedules the unlock after
+// the delay, returning when it takes effect
+func (s *AppState) ConfirmUnlock(typed string) (time.Time, error) {
+	if !s.IsSessionActive() || s.ActiveSession.Unlock == nil {
+		return time.Time{}, fmt.Errorf("no emergency unlock was requested")
+	}
+	u := s.ActiveSession.Unlock
+	if u.Confirmed() {
+		return u.UnlockAt, nil
+	}
+	typedHash := hashChallenge(strings.Join(strings.Fields(typed), ""))
+	if subtle.ConstantTimeCompare([]byte(typedHash), []byte(u.ChallengeHash)) != 1 {
+		return time.Time{}, ErrWrongChallenge
+	}
+
+	now, _ := s.ActiveSession.Now(clock.Read())
+	u.UnlockAt = now.Add(s.GetUnlockDelay())
+	s.RecordEvent(EventUnlock, fmt.Sprintf("emergency unlock confirmed, unblocking at %s", u.UnlockAt.Format(time.DateTime)))
+	return u.UnlockAt, nil
+}
+
+// CancelUnlock withdraws an emergency unlock request
+func (s *AppState) CancelUnlock() error {
+	if s.ActiveSession == nil || s.ActiveSession.Unlock == nil {
+		return fmt.Errorf("no emergency unlock was requested")
+	}
+	if s.ActiveSession.Unlock.Confirmed() {
+		s.RecordEvent(EventUnlock, "emergency unlock cancelled")
+	}
+	s.ActiveSession.Unlock = nil
+	return nil
+}
+
+// UnlockRemaining returns the time until a confirmed emergency unlock takes
+// effect; ok is false if none is pending
+func (s *AppState) UnlockRemaining() (remaining time.Duration, ok bool) {
+	if s.ActiveSession == nil {
+		return 0, false
+	}
+	return s.ActiveSession.UnlockRemaining(clock.Read())
+}
+
+// UnlockRemaining returns the time at r until a confirmed emergency unlock
+// takes effect; ok is false if none is pending
+func (s *Session) UnlockRemaining(r clock.Reading) (remaining time.Duration, ok bool) {
+	if s.Unlock == nil || !s.Unlock.Confirmed() {
+		return 0, false
+	}
+	now, _ := s.Now(r)
+	return max(s.Unlock.UnlockAt.Sub(now), 0), true
+}
+
+// SessionOver reports whether the active session should end now, and why:
+// EndExpired when its time is up, EndCancelled when an emergency unlock is
+// due
+func (s *AppState) SessionOver() (reason string, over bool) {
+	if s.ActiveSession == nil {
+		return "", false
+	}
+	if !s.IsSessionActive() {
+		return EndExpired, true
+	}
+	if remaining, ok := s.UnlockRemaining(); ok && remaining == 0 {
+		return EndCancelled, true
+	}
+	return "", false
+}
+
+// hashChallenge returns the hex encoded SHA-256 of a challenge
+func hashChallenge(challenge string) string {
+	sum := sha256.Sum256([]byte(challenge))
+	return hex.EncodeToString(sum[:])
+}
+
+// newChallenge returns a random challenge string
+func newChallenge() (string, error) {
+	var b strings.Builder
+	limit := big.NewInt(int64(len(challengeAlphabet)))
+	for i := 0; i < challengeLength; i++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(challengeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnlockChallengeIsNotStored(t *testing.T) {
+	useTempState(t)
+
+	st := &AppState{CurrentProfile: DefaultProfileName}
+	st.StartSession(time.Hour, "1 hour", nil)
+
+	challenge, err := st.RequestUnlock()
+	if err != nil {
+		t.Fatalf("RequestUnlock: %v", err)
+	}
+	if len(challenge) != challengeLength {
+		t.Fatalf("challenge %q has %d characters, want %d", challenge, len(challenge), challengeLength)
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), challenge) {
+		t.Errorf("state contains the challenge: %s", data)
+	}
+
+	if _, err := st.ConfirmUnlock("wrong"); !errors.Is(err, ErrWrongChallenge) {
+		t.Errorf("ConfirmUnlock(wrong) = %v, want ErrWrongChallenge", err)
+	}
+
+	// Spaces typed in between are ignored
+	typed := challenge[:10] + " " + challenge[10:]
+	at, err := st.ConfirmUnlock(typed)
+	if err != nil {
+		t.Fatalf("ConfirmUnlock: %v", err)
+	}
+	if at.IsZero() {
+		t.Error("confirmed unlock has no time")
+	}
+	if _, err := st.RequestUnlock(); err == nil {
+		t.Error("RequestUnlock replaced a confirmed unlock")
+	}
+}
+
+func TestRequestUnlockReplacesChallenge(t *testing.T) {
+	useTempState(t)
+
+	st := &AppState{CurrentProfile: DefaultProfileName}
+	st.StartSession(time.Hour, "1 hour", nil)
+
+	first, err := st.RequestUnlock()
+	if err != nil {
+		t.Fatalf("RequestUnlock: %v", err)
+	}
+	second, err := st.RequestUnlock()
+	if err != nil {
+		t.Fatalf("RequestUnlock: %v", err)
+	}
+	if first == second {
+		t.Fatal("requesting again returned the same challenge")
+	}
+
+	if _, err := st.ConfirmUnlock(first); !errors.Is(err, ErrWrongChallenge) {
+		t.Errorf("ConfirmUnlock(first) = %v, want ErrWrongChallenge", err)
+	}
+	if _, err := st.ConfirmUnlock(second); err != nil {
+		t.Errorf("ConfirmUnlock(second): %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package stats
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// Period is the focused time within a day or week
+type Period struct {
+	Start   time.Time
+	Focused time.Duration
+}
+
+// DomainCount is how many sessions blocked a domain or pattern
+type DomainCount struct {
+	Domain   string
+	Sessions int
+}
+
+// Summary aggregates the session history
+type Summary struct {
+	// Days and Weeks cover the most recent days and weeks, oldest first;
+	// weeks start on Monday
+	Days  []Period
+	Weeks []Period
+
+	Total    time.Duration
+	Sessions int
+
+	// LongestStreak and CurrentStreak count consecutive days with focus
+	LongestStreak int
+	CurrentStreak int
+
+	// Ended counts sessions by end reason
+	Ended map[string]int
+
+	// TopDomains are the most frequently blocked domains, most first
+	TopDomains []DomainCount
+}
+
+// Summarize aggregates sessions over the given number of recent days and
+// weeks as seen at now, listing up to top domains
+func Summarize(sessions []state.HistoryEntry, now time.Time, days, weeks, top int) Summary {
+	sum := Summary{Ended: make(map[string]int)}
+
+	today := midnight(now)
+	for i := days - 1; i >= 0; i-- {
+		sum.Days = append(sum.Days, Period{Start: today.AddDate(0, 0, -i)})
+	}
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	for i := weeks - 1; i >= 0; i-- {
+		sum.Weeks = append(sum.Weeks, Period{Start: monday.AddDate(0, 0, -7*i)})
+	}
+
+	focusedDays := make(map[time.Time]bool)
+	domains := make(map[string]int)
+
+	for _, e := range sessions {
+		focused := e.Focused(now)
+		if focused <= 0 {
+			continue
+		}
+		end := e.Start.Add(focused)
+
+		sum.Sessions++
+		sum.Total += focused
+		if e.EndReason != "" {
+			sum.Ended[e.EndReason]++
+		}
+
+		for i := range sum.Days {
+			sum.Days[i].Focused += overlap(e.Start, end, sum.Days[i].Start, sum.Days[i].Start.AddDate(0, 0, 1))
+		}
+		for i := range sum.Weeks {
+			sum.Weeks[i].Focused += overlap(e.Start, end, sum.Weeks[i].Start, sum.Weeks[i].Start.AddDate(0, 0, 7))
+		}
+
+		// A session counts for every day it touched
+		for day := midnight(e.Start.In(now.Location())); day.Before(end); day = day.AddDate(0, 0, 1) {
+			focusedDays[day] = true
+		}
+
+		seen := make(map[string]bool)
+		for _, url := range e.URLs {
+			url = strings.ToLower(url)
+			if !seen[url] {
+				seen[url] = true
+				domains[url]++
+			}
+		}
+	}
+
+	sum.LongestStreak, sum.CurrentStreak = streaks(focusedDays, today)
+
+	for domain, n := range domains {
+		sum.TopDomains = append(sum.TopDomains, DomainCount{Domain: domain, Sessions: n})
+	}
+	slices.SortFunc(sum.TopDomains, func(a, b DomainCount) int {
+		if a.Sessions != b.Sessions {
+			return b.Sessions - a.Sessions
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+	if len(sum.TopDomains) > top {
+		sum.TopDomains = sum.TopDomains[:top]
+	}
+
+	return sum
+}
+
+// midnight returns the start of t's day in t's location
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// overlap returns how much of [start, end) falls within [from, to)
+func overlap(start, end, from, to time.Time) time.Duration {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	return max(end.Sub(start), 0)
+}
+
+// streaks returns the longest run of consecutive focused days and the run
+// ending today (or yesterday, if today has no focus yet)
+func streaks(focused map[time.Time]bool, today time.Time) (longest, current int) {
+	var days []time.Time
+	for day := range focused {
+		days = append(days, day)
+	}
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+
+	run := 0
+	for i, day := range days {
+		if i > 0 && days[i-1].AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+
+	day := today
+	if !focused[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for focused[day] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return longest, current
+}
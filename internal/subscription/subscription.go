@@ -0,0 +1,130 @@
+package subscription
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/blocklist"
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// Fetch limits
+const (
+	// fetchTimeout bounds a single download
+	fetchTimeout = 30 * time.Second
+
+	// maxListSize bounds a downloaded list; the largest curated hosts files
+	// are a few megabytes
+	maxListSize = 64 * 1024 * 1024
+
+	// RetryInterval is how soon a failed fetch is retried, unless the
+	// refresh interval is shorter
+	RetryInterval = 15 * time.Minute
+)
+
+// Fetcher downloads subscribed lists into the on-disk cache
+type Fetcher struct {
+	// Client performs the requests; replace it to use a stand-in server
+	Client *http.Client
+}
+
+// NewFetcher returns a fetcher with a bounded request timeout
+func NewFetcher() *Fetcher {
+	return &Fetcher{Client: &http.Client{Timeout: fetchTimeout}}
+}
+
+// Due returns when a list should be fetched next, zero if it never was
+func Due(sub state.Subscription, cache *state.SubscriptionCache) time.Time {
+	if cache == nil || cache.CheckedAt.IsZero() {
+		return time.Time{}
+	}
+	interval := sub.Interval()
+	if cache.Error != "" {
+		interval = min(interval, RetryInterval)
+	}
+	return cache.CheckedAt.Add(interval)
+}
+
+// Refresh fetches a list unless the server reports it unchanged since the
+// cached copy, and reports whether the cached domains changed
+// On failure the cached copy is kept, so its domains stay blocked offline.
+func (f *Fetcher) Refresh(listURL string) (changed bool, err error) {
+	cache, err := state.LoadSubscriptionCache(listURL)
+	if err != nil || cache == nil {
+		cache = &state.SubscriptionCache{URL: listURL}
+	}
+	cache.CheckedAt = time.Now()
+
+	domains, err := f.fetch(cache)
+	if err != nil {
+		cache.Error = err.Error()
+		if saveErr := state.SaveSubscriptionCache(cache, nil); saveErr != nil {
+			return false, saveErr
+		}
+		return false, err
+	}
+
+	cache.Error = ""
+	cache.FetchedAt = cache.CheckedAt
+	if domains == nil {
+		// Not modified
+		return false, state.SaveSubscriptionCache(cache, nil)
+	}
+
+	old, _ := state.SubscriptionDomains(listURL)
+	cache.Domains = len(domains)
+	if err := state.SaveSubscriptionCache(cache, domains); err != nil {
+		return false, err
+	}
+	return !slices.Equal(old, domains), nil
+}
+
+// fetch downloads and parses the list, sending the cache's validators
+// It returns nil domains if the list wasn't modified, and updates the
+// validators otherwise.
+func (f *Fetcher) fetch(cache *state.SubscriptionCache) ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, cache.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validators only help if the domains they describe are still cached
+	if cache.Domains > 0 {
+		if cache.ETag != "" {
+			req.Header.Set("If-None-Match", cache.ETag)
+		}
+		if cache.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cache.LastModified)
+		}
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", cache.URL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("failed to fetch %s: %s", cache.URL, resp.Status)
+	}
+
+	src, err := blocklist.Parse(cache.URL, io.LimitReader(resp.Body, maxListSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(src.Domains) == 0 {
+		// Most likely an error page; keep the last good copy
+		return nil, fmt.Errorf("%s contains no domains", cache.URL)
+	}
+
+	cache.ETag = resp.Header.Get("ETag")
+	cache.LastModified = resp.Header.Get("Last-Modified")
+	return src.Domains, nil
+}
@@ -0,0 +1,176 @@
+package subscription
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// useTempCache points the subscription cache at a temporary directory
+func useTempCache(t *testing.T) {
+	t.Helper()
+	old := state.SubscriptionDir
+	state.SubscriptionDir = t.TempDir()
+	t.Cleanup(func() { state.SubscriptionDir = old })
+}
+
+// cachedDomains returns the cached domains of a list
+func cachedDomains(t *testing.T, listURL string) []string {
+	t.Helper()
+	domains, err := state.SubscriptionDomains(listURL)
+	if err != nil {
+		t.Fatalf("SubscriptionDomains: %v", err)
+	}
+	return domains
+}
+
+func TestRefreshNotModified(t *testing.T) {
+	useTempCache(t)
+
+	const etag = `"v1"`
+	const lastModified = "Mon, 05 Oct 2026 10:00:00 GMT"
+	var requests []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte("0.0.0.0 ads.example\n||tracker.example^\nplain.example\n"))
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{Client: srv.Client()}
+	changed, err := f.Refresh(srv.URL)
+	if err != nil || !changed {
+		t.Fatalf("first Refresh = %v, %v; want true, nil", changed, err)
+	}
+	want := []string{"ads.example", "tracker.example", "plain.example"}
+	if got := cachedDomains(t, srv.URL); !slices.Equal(got, want) {
+		t.Errorf("cached domains = %v, want %v", got, want)
+	}
+
+	changed, err = f.Refresh(srv.URL)
+	if err != nil || changed {
+		t.Fatalf("second Refresh = %v, %v; want false, nil", changed, err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := requests[1].Header.Get("If-None-Match"); got != etag {
+		t.Errorf("If-None-Match = %q, want %q", got, etag)
+	}
+	if got := requests[1].Header.Get("If-Modified-Since"); got != lastModified {
+		t.Errorf("If-Modified-Since = %q, want %q", got, lastModified)
+	}
+	if got := cachedDomains(t, srv.URL); !slices.Equal(got, want) {
+		t.Errorf("cached domains after 304 = %v, want %v", got, want)
+	}
+
+	cache, err := state.LoadSubscriptionCache(srv.URL)
+	if err != nil || cache == nil {
+		t.Fatalf("LoadSubscriptionCache = %v, %v", cache, err)
+	}
+	if cache.Error != "" || cache.Domains != len(want) {
+		t.Errorf("cache = %+v, want no error and %d domains", cache, len(want))
+	}
+}
+
+func TestRefreshFailureKeepsCache(t *testing.T) {
+	useTempCache(t)
+
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		w.Write([]byte("a.example\nb.example\n"))
+	}))
+	listURL := srv.URL
+	f := &Fetcher{Client: srv.Client()}
+	if _, err := f.Refresh(listURL); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	want := cachedDomains(t, listURL)
+
+	// Server error
+	status = http.StatusServiceUnavailable
+	if changed, err := f.Refresh(listURL); err == nil || changed {
+		t.Errorf("Refresh on 503 = %v, %v; want false and an error", changed, err)
+	}
+	if got := cachedDomains(t, listURL); !slices.Equal(got, want) {
+		t.Errorf("cached domains after 503 = %v, want %v", got, want)
+	}
+
+	// Offline
+	srv.Close()
+	if changed, err := f.Refresh(listURL); err == nil || changed {
+		t.Errorf("Refresh while offline = %v, %v; want false and an error", changed, err)
+	}
+	if got := cachedDomains(t, listURL); !slices.Equal(got, want) {
+		t.Errorf("cached domains while offline = %v, want %v", got, want)
+	}
+
+	cache, err := state.LoadSubscriptionCache(listURL)
+	if err != nil || cache == nil {
+		t.Fatalf("LoadSubscriptionCache = %v, %v", cache, err)
+	}
+	if cache.Error == "" {
+		t.Error("cache doesn't record the failure")
+	}
+	sub := state.Subscription{URL: listURL}
+	if due := Due(sub, cache); due.Sub(cache.CheckedAt) != RetryInterval {
+		t.Errorf("failed list due after %s, want %s", due.Sub(cache.CheckedAt), RetryInterval)
+	}
+}
+
+func TestRefreshRejectsEmptyList(t *testing.T) {
+	useTempCache(t)
+
+	body := "a.example\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{Client: srv.Client()}
+	if _, err := f.Refresh(srv.URL); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// An error page without domains mustn't replace the list
+	body = "<html><body>Service unavailable</body></html>\n"
+	if changed, err := f.Refresh(srv.URL); err == nil || changed {
+		t.Errorf("Refresh of empty list = %v, %v; want false and an error", changed, err)
+	}
+	if got := cachedDomains(t, srv.URL); !slices.Equal(got, []string{"a.example"}) {
+		t.Errorf("cached domains = %v, want [a.example]", got)
+	}
+}
+
+func TestDue(t *testing.T) {
+	checked := time.Date(2026, 10, 5, 10, 0, 0, 0, time.UTC)
+	sub := state.Subscription{URL: "https://lists.example/hosts", Refresh: "6h"}
+
+	tests := []struct {
+		name  string
+		cache *state.SubscriptionCache
+		want  time.Time
+	}{
+		{"never fetched", nil, time.Time{}},
+		{"fetched", &state.SubscriptionCache{CheckedAt: checked}, checked.Add(6 * time.Hour)},
+		{"failed", &state.SubscriptionCache{CheckedAt: checked, Error: "offline"}, checked.Add(RetryInterval)},
+	}
+	for _, tt := range tests {
+		if got := Due(sub, tt.cache); !got.Equal(tt.want) {
+			t.Errorf("%s: Due = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
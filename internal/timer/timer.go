@@ -2,6 +2,9 @@ package timer
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -9,6 +12,9 @@ import (
 type Duration struct {
 	Label    string
 	Duration time.Duration
+
+	// End is when a parsed duration ends; it is zero for predefined ones
+	End time.Time
 }
 
 // PredefinedDurations returns the list of available durations
@@ -40,3 +46,180 @@ func FormatDuration(d time.Duration) string {
 		return fmt.Sprintf("%ds", seconds)
 	}
 }
+
+// Limits for custom durations and end times, described by DurationLimits
+const (
+	MinDuration = 30 * time.Second
+	MaxDuration = 7 * 24 * time.Hour
+
+	// MaxUntil bounds end times, which may name a date weeks ahead on
+	// purpose but can still be mistyped
+	MaxUntil = 366 * 24 * time.Hour
+
+	DurationLimits = "between 30 seconds and 7 days, or until an end time within a year"
+)
+
+// Parse parses a custom duration relative to now
+// It accepts Go-style durations such as "90m" or "2h 30m", and end times:
+// "until 17:30" (the next time the clock shows it), "until 18:00 today",
+// "until tomorrow 9am", "until Friday 17:00" or "until 2026-12-24 12:00".
+// End times are interpreted in now's location, and may be further ahead
+// than durations.
+func Parse(input string, now time.Time) (Duration, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return Duration{}, fmt.Errorf("no duration given")
+	}
+
+	var d Duration
+	limit := MaxDuration
+	if rest, ok := strings.CutPrefix(input, "until "); ok {
+		limit = MaxUntil
+		end, err := parseEnd(strings.Fields(rest), now)
+		if err != nil {
+			return Duration{}, err
+		}
+		d = Duration{Label: "until " + formatEnd(end, now), Duration: end.Sub(now), End: end}
+	} else {
+		parsed, err := time.ParseDuration(strings.ReplaceAll(input, " ", ""))
+		if err != nil {
+			return Duration{}, fmt.Errorf("invalid duration %q, expected e.g. 90m, 2h30m or until 17:30", input)
+		}
+		d = Duration{Label: FormatDuration(parsed), Duration: parsed, End: now.Add(parsed)}
+	}
+
+	if d.Duration < MinDuration || d.Duration > limit {
+		return Duration{}, fmt.Errorf("duration must be %s", DurationLimits)
+	}
+	return d, nil
+}
+
+// clockLayouts are the accepted time-of-day formats
+var clockLayouts = []string{"15:04", "15.04", "3:04pm", "3pm", "3:04 pm", "3 pm"}
+
+// weekdays maps day names to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// parseEnd parses the words after "until" into an end time after now
+// The day may come before or after the time of day.
+func parseEnd(words []string, now time.Time) (time.Time, error) {
+	if len(words) == 0 {
+		return time.Time{}, fmt.Errorf("no end time given")
+	}
+
+	// Split off the day word; the rest is the time of day
+	day, clock := "", words
+	for _, i := range []int{0, len(words) - 1} {
+		w := words[i]
+		_, weekday := weekdays[w]
+		_, dateErr := time.Parse("2006-01-02", w)
+		if w == "today" || w == "tomorrow" || weekday || dateErr == nil {
+			day = w
+			clock = slices.Delete(slices.Clone(words), i, i+1)
+			break
+		}
+	}
+
+	hour, minute := 0, 0
+	if len(clock) == 0 && !isDate(day) {
+		return time.Time{}, fmt.Errorf("no time of day given, e.g. until %s 17:00", day)
+	}
+	if len(clock) > 0 {
+		t, err := parseClock(strings.Join(clock, " "))
+		if err != nil {
+			return time.Time{}, err
+		}
+		hour, minute = t.Hour(), t.Minute()
+	}
+
+	// Build the end from the calendar date and wall-clock time, so it is
+	// correct across DST changes
+	at := func(daysAhead int) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day()+daysAhead, hour, minute, 0, 0, now.Location())
+	}
+
+	var end time.Time
+	switch {
+	case day == "":
+		end = at(0)
+		if !end.After(now) {
+			end = at(1)
+		}
+	case day == "today":
+		end = at(0)
+	case day == "tomorrow":
+		end = at(1)
+	case isDate(day):
+		date, _ := time.Parse("2006-01-02", day)
+		end = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, now.Location())
+	default:
+		ahead := (int(weekdays[day]) - int(now.Weekday()) + 7) % 7
+		end = at(ahead)
+		if !end.After(now) {
+			end = at(ahead + 7)
+		}
+	}
+
+	if !end.After(now) {
+		return time.Time{}, fmt.Errorf("%s is in the past", end.Format("2006-01-02 15:04"))
+	}
+	return end, nil
+}
+
+// isDate reports whether s is a calendar date like 2026-12-24
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+// parseClock parses a time of day
+func parseClock(s string) (time.Time, error) {
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected e.g. 17:30 or 5:30pm", s)
+}
+
+// formatEnd formats an end time compactly relative to now
+func formatEnd(end, now time.Time) string {
+	y, m, d := end.Date()
+	ny, nm, nd := now.Date()
+	switch {
+	case y == ny && m == nm && d == nd:
+		return end.Format("15:04")
+	case end.Sub(now) < 6*24*time.Hour:
+		return end.Format("Mon 15:04")
+	default:
+		return end.Format("Jan 2 15:04")
+	}
+}
+
+// LocalZone returns the local time zone, loaded by its IANA name when that
+// can be determined, so it can be recorded and loaded again later
+func LocalZone() *time.Location {
+	name := strings.TrimPrefix(os.Getenv("TZ"), ":")
+	if name == "" {
+		if target, err := os.Readlink("/etc/localtime"); err == nil {
+			if _, after, ok := strings.Cut(target, "zoneinfo/"); ok {
+				name = after
+			}
+		}
+	}
+
+	if name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
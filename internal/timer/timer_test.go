@@ -0,0 +1,158 @@
+package timer
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseDuration(t *testing.T) {
+	now := time.Date(2026, time.October, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  time.Duration
+		label string
+		ok    bool
+	}{
+		{"90m", 90 * time.Minute, "1h 30m 0s", true},
+		{"2h30m", 150 * time.Minute, "2h 30m 0s", true},
+		{"2h 30m", 150 * time.Minute, "2h 30m 0s", true},
+		{"  1H  15M ", 75 * time.Minute, "1h 15m 0s", true},
+		{"45s", 45 * time.Second, "45s", true},
+
+		// Bounds are inclusive
+		{"30s", MinDuration, "30s", true},
+		{"29s", 0, "", false},
+		{"168h", MaxDuration, "168h 0m 0s", true},
+		{"168h1s", 0, "", false},
+		{"-5m", 0, "", false},
+		{"0", 0, "", false},
+
+		// Invalid input
+		{"", 0, "", false},
+		{"   ", 0, "", false},
+		{"soon", 0, "", false},
+		{"10 minutes", 0, "", false},
+		{"1d", 0, "", false},
+		{"90", 0, "", false},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.input, now)
+		if (err == nil) != tt.ok {
+			t.Errorf("Parse(%q) error = %v, want ok %v", tt.input, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if d.Duration != tt.want || d.Label != tt.label || !d.End.Equal(now.Add(tt.want)) {
+			t.Errorf("Parse(%q) = %+v, want %s labelled %q", tt.input, d, tt.want, tt.label)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{1499 * time.Millisecond, "1s"},
+		{5*time.Minute + 3*time.Second, "5m 3s"},
+		{26*time.Hour + 30*time.Second, "26h 0m 30s"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestParseUntil(t *testing.T) {
+	// Monday, October 5th 2026
+	now := time.Date(2026, time.October, 5, 10, 0, 0, 0, time.UTC)
+	date := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		input string
+		want  time.Time
+		ok    bool
+	}{
+		{"until 17:30", date(time.October, 5, 17, 30), true},
+		{"until 9:00", date(time.October, 6, 9, 0), true},
+		{"until 5:30pm", date(time.October, 5, 17, 30), true},
+		{"until 18:00 today", date(time.October, 5, 18, 0), true},
+		{"until today 9:00", time.Time{}, false},
+		{"until tomorrow 9am", date(time.October, 6, 9, 0), true},
+		{"until friday 17:00", date(time.October, 9, 17, 0), true},
+		{"until Mon 9:00", date(time.October, 12, 9, 0), true},
+
+		// Dates may be further ahead than durations, up to a year
+		{"until 2026-10-07", date(time.October, 7, 0, 0), true},
+		{"until 2026-12-24 12:00", date(time.December, 24, 12, 0), true},
+		{"until 12:00 2026-12-24", date(time.December, 24, 12, 0), true},
+		{"until 2027-10-05 09:00", time.Date(2027, time.October, 5, 9, 0, 0, 0, time.UTC), true},
+		{"until 2027-10-06 10:01", time.Time{}, false},
+		{"until 2026-10-01 12:00", time.Time{}, false},
+
+		// Ends closer than MinDuration are refused
+		{"until 10:00", date(time.October, 6, 10, 0), true},
+		{"until today 10:00", time.Time{}, false},
+
+		// Invalid input
+		{"until", time.Time{}, false},
+		{"until friday", time.Time{}, false},
+		{"until 25:00", time.Time{}, false},
+		{"until someday 9:00", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.input, now)
+		if (err == nil) != tt.ok {
+			t.Errorf("Parse(%q) error = %v, want ok %v", tt.input, err, tt.ok)
+			continue
+		}
+		if tt.ok && (!d.End.Equal(tt.want) || d.Duration != tt.want.Sub(now)) {
+			t.Errorf("Parse(%q) = %s (%s), want %s", tt.input, d.End, d.Duration, tt.want)
+		}
+	}
+}
+
+func TestParseUntilAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		input string
+		end   time.Time
+		dur   time.Duration
+	}{
+		// Clocks go back an hour in the night to Sunday, October 25th
+		{"fall back", time.Date(2026, time.October, 24, 12, 0, 0, 0, berlin), "until sunday 12:00",
+			time.Date(2026, time.October, 25, 12, 0, 0, 0, berlin), 25 * time.Hour},
+		// Clocks go forward an hour in the night to Sunday, March 29th
+		{"spring forward", time.Date(2026, time.March, 28, 9, 0, 0, 0, berlin), "until tomorrow 9:00",
+			time.Date(2026, time.March, 29, 9, 0, 0, 0, berlin), 23 * time.Hour},
+		{"date", time.Date(2026, time.October, 20, 12, 0, 0, 0, berlin), "until 2026-11-03 12:00",
+			time.Date(2026, time.November, 3, 12, 0, 0, 0, berlin), 14*24*time.Hour + time.Hour},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.input, tt.now)
+		if err != nil {
+			t.Errorf("%s: Parse(%q): %v", tt.name, tt.input, err)
+			continue
+		}
+		if !d.End.Equal(tt.end) || d.Duration != tt.dur {
+			t.Errorf("%s: Parse(%q) = %s (%s), want %s (%s)", tt.name, tt.input, d.End, d.Duration, tt.end, tt.dur)
+		}
+		if h, m, _ := d.End.Clock(); h != tt.end.Hour() || m != 0 {
+			t.Errorf("%s: end is %02d:%02d wall-clock time", tt.name, h, m)
+		}
+	}
+}
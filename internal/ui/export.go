@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phil/selfcontrol/internal/blocklist"
+	"github.com/phil/selfcontrol/internal/state"
+)
+
+// exportPlaceholder is shown in the empty export input
+const exportPlaceholder = "~/blocklists/work.txt"
+
+// exportOptions are the choices of the export view
+type exportOptions struct {
+	format int
+	all    bool
+
+	// written describes the last file written
+	written string
+}
+
+// handleExportKeys processes keys in the export view
+func (m Model) handleExportKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.textInput.Placeholder = urlPlaceholder
+		m.mode = viewMain
+		return m, nil
+
+	case "tab":
+		m.export.format = (m.export.format + 1) % len(blocklist.Formats)
+		return m, nil
+
+	case "ctrl+t":
+		m.export.all = !m.export.all
+		return m, nil
+
+	case "enter":
+		path := strings.TrimSpace(m.textInput.Value())
+		if path == "" {
+			return m, nil
+		}
+
+		profiles := []*state.Profile{m.state.Current()}
+		if m.export.all {
+			profiles = m.state.Profiles
+		}
+		format := blocklist.Formats[m.export.format]
+		if err := blocklist.WriteFile(expandHome(path), format, blocklist.Lists(profiles)); err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.err = nil
+		m.export.written = fmt.Sprintf("Exported %s as %s to %s", plural(len(profiles), "profile"), format, path)
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
+	}
+}
+
+// renderExportView renders the export prompt and options
+func (m Model) renderExportView() string {
+	var s strings.Builder
+
+	borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("142"))
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("184")).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
+	resultStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BB7D"))
+	exampleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	const tableWidth = 120
+	const contentWidth = tableWidth - 4
+
+	row := func(text string, style lipgloss.Style) {
+		if len(text) > contentWidth {
+			text = text[:contentWidth-3] + "..."
+		}
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(style.Render(fmt.Sprintf("%-*s", contentWidth, text)))
+		s.WriteString(borderStyle.Render(" │"))
+		s.WriteString("\n")
+	}
+
+	s.WriteString(borderStyle.Render("┌ Export "))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-10)))
+	s.WriteString(borderStyle.Render("┐"))
+	s.WriteString("\n")
+
+	// Format choice, with the selected one highlighted
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render("Format: "))
+	for i, format := range blocklist.Formats {
+		if i == m.export.format {
+			s.WriteString(selectedStyle.Render("[" + format + "]"))
+		} else {
+			s.WriteString(exampleStyle.Render(" " + format + " "))
+		}
+		s.WriteString(" ")
+	}
+	s.WriteString("\n")
+
+	scope := "profile " + m.state.CurrentProfile
+	if m.export.all {
+		scope = "all profiles"
+	}
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render("Export: "))
+	s.WriteString(scope)
+	s.WriteString("\n")
+
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render("Path:   "))
+	s.WriteString(m.textInput.View())
+	s.WriteString("\n")
+
+	row("", lipgloss.NewStyle())
+	if m.export.written != "" {
+		row(m.export.written, resultStyle)
+	} else {
+		for _, line := range []string{
+			"  txt    - one URL or pattern per line",
+			"  json   - profiles with their names, modes and URLs",
+			"  hosts  - 0.0.0.0 lines for /etc/hosts; patterns are kept as comments",
+			"All formats can be imported again with 'i' or selfcontrol import.",
+		} {
+			row(line, exampleStyle)
+		}
+	}
+
+	s.WriteString(borderStyle.Render("└"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-2)))
+	s.WriteString(borderStyle.Render("┘"))
+	s.WriteString("\n\n")
+
+	// Command bar
+	cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	cmdKeyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
+
+	s.WriteString(cmdKeyStyle.Render("Enter") + " " + cmdStyle.Render("Export"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Tab") + " " + cmdStyle.Render("Format"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Ctrl+T") + " " + cmdStyle.Render("Current/all profiles"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Esc") + " " + cmdStyle.Render("Back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
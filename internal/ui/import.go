@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phil/selfcontrol/internal/blocklist"
+)
+
+// importPlaceholder is shown in the empty import input
+const importPlaceholder = "~/Downloads/hosts social.txt"
+
+// importResult is the outcome of the last import, shown in the import view
+type importResult struct {
+	sources  []*blocklist.Source
+	imported int
+}
+
+// handleImportKeys processes keys in the import view
+func (m Model) handleImportKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.textInput.Placeholder = urlPlaceholder
+		m.mode = viewMain
+		m.cursor = 0
+		return m, nil
+
+	case "enter":
+		paths := strings.Fields(m.textInput.Value())
+		if len(paths) == 0 {
+			return m, nil
+		}
+
+		result, err := m.importFiles(paths)
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.err = nil
+		m.imported = result
+		m.textInput.SetValue("")
+		m.refresh()
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
+	}
+}
+
+// importFiles parses blocklist files and adds their new domains to the
+// current profile
+func (m Model) importFiles(paths []string) (*importResult, error) {
+	var sources []*blocklist.Source
+	for _, path := range paths {
+		src, err := blocklist.ParseFile(expandHome(path))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+
+	urls := blocklist.Merge(sources, m.urls())
+	if len(urls) > 0 {
+		if err := m.service.AddURLs(urls); err != nil {
+			return nil, err
+		}
+	}
+	return &importResult{sources: sources, imported: len(urls)}, nil
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/') {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// renderImportView renders the blocklist import prompt and the last result
+func (m Model) renderImportView() string {
+	var s strings.Builder
+
+	borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("142"))
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("184")).Bold(true)
+	resultStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BB7D"))
+	exampleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	const tableWidth = 120
+	const contentWidth = tableWidth - 4
+
+	row := func(text string, style lipgloss.Style) {
+		if len(text) > contentWidth {
+			text = text[:contentWidth-3] + "..."
+		}
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(style.Render(fmt.Sprintf("%-*s", contentWidth, text)))
+		s.WriteString(borderStyle.Render(" │"))
+		s.WriteString("\n")
+	}
+
+	title := fmt.Sprintf("┌ Import Blocklist · %s ", m.state.CurrentProfile)
+	s.WriteString(borderStyle.Render(title))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", max(tableWidth-lipgloss.Width(title)-1, 0))))
+	s.WriteString(borderStyle.Render("┐"))
+	s.WriteString("\n")
+
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render("Files: "))
+	s.WriteString(m.textInput.View())
+	s.WriteString("\n")
+
+	row("", lipgloss.NewStyle())
+	if r := m.imported; r != nil {
+		for _, src := range r.sources {
+			row(fmt.Sprintf("%s: %s", src.Name, src.Summary()), resultStyle)
+		}
+		row(fmt.Sprintf("Imported %d URLs", r.imported), headerStyle)
+	} else {
+		for _, line := range []string{
+			"Supported formats, also mixed in one file:",
+			"  0.0.0.0 example.com    - hosts files such as StevenBlack's lists",
+			"  example.com            - one domain or pattern per line",
+			"  ||example.com^         - Adblock Plus domain rules; rules with paths or options are skipped",
+			"Separate several files with spaces. Entries already in the profile are skipped.",
+		} {
+			row(line, exampleStyle)
+		}
+	}
+
+	s.WriteString(borderStyle.Render("└"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-2)))
+	s.WriteString(borderStyle.Render("┘"))
+	s.WriteString("\n\n")
+
+	// Command bar
+	cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	cmdKeyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
+
+	s.WriteString(cmdKeyStyle.Render("Enter") + " " + cmdStyle.Render("Import"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Esc") + " " + cmdStyle.Render("Back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
@@ -0,0 +1,281 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phil/selfcontrol/internal/state"
+	"github.com/phil/selfcontrol/internal/timer"
+)
+
+// handleProfileKeys processes keys in the profile switcher
+func (m Model) handleProfileKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	profiles := m.state.Profiles
+
+	switch msg.String() {
+	case "esc":
+		m.mode = viewMain
+		m.cursor = 0
+		return m, nil
+
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.cursor < len(profiles)-1 {
+			m.cursor++
+		}
+		return m, nil
+
+	case "enter":
+		// Switch to the selected profile
+		if m.cursor < len(profiles) {
+			if err := m.service.SelectProfile(profiles[m.cursor].Name); err != nil {
+				m.err = err
+			}
+			m.refresh()
+		}
+		m.mode = viewMain
+		m.cursor = 0
+		return m, nil
+
+	case "n":
+		// Create a new profile
+		m.mode = viewNewProfile
+		m.textInput.SetValue("")
+		m.textInput.Placeholder = "deep work"
+		m.textInput.Focus()
+		return m, nil
+
+	case "m":
+		// Switch the selected profile between blocklist and allowlist
+		if m.cursor < len(profiles) {
+			mode := state.ModeAllow
+			if profiles[m.cursor].Allowlist() {
+				mode = state.ModeBlock
+			}
+			if err := m.service.SetProfileMode(profiles[m.cursor].Name, mode); err != nil {
+				m.err = err
+			}
+			m.refresh()
+		}
+		return m, nil
+
+	case "x":
+		// Delete the selected profile
+		if m.cursor < len(profiles) {
+			if err := m.service.RemoveProfile(profiles[m.cursor].Name); err != nil {
+				m.err = err
+			}
+			m.refresh()
+			if m.cursor >= len(m.state.Profiles) {
+				m.cursor = len(m.state.Profiles) - 1
+			}
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+// handleNewProfileKeys processes keys while naming a new profile
+func (m Model) handleNewProfileKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		name := strings.TrimSpace(m.textInput.Value())
+		if name != "" {
+			if err := m.service.AddProfile(name); err != nil {
+				m.err = err
+			}
+			m.refresh()
+		}
+		m.textInput.Placeholder = urlPlaceholder
+		m.mode = viewProfiles
+		m.cursor = m.profileIndex(name)
+		return m, nil
+
+	case "esc":
+		m.textInput.Placeholder = urlPlaceholder
+		m.mode = viewProfiles
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
+	}
+}
+
+// currentProfileIndex returns the index of the current profile
+func (m Model) currentProfileIndex() int {
+	return m.profileIndex(m.state.CurrentProfile)
+}
+
+// profileIndex returns the index of the named profile, or 0
+func (m Model) profileIndex(name string) int {
+	for i, p := range m.state.Profiles {
+		if p.Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// renderProfilesView renders the profile switcher
+func (m Model) renderProfilesView() string {
+	var s strings.Builder
+
+	borderColor := lipgloss.Color("142")
+	headerColor := lipgloss.Color("184")
+	highlightBg := lipgloss.Color("237")
+	selectedBg := lipgloss.Color("235")
+
+	borderStyle := lipgloss.NewStyle().Foreground(borderColor)
+	headerStyle := lipgloss.NewStyle().Foreground(headerColor).Bold(true)
+
+	const tableWidth = 120
+
+	// Title
+	s.WriteString(borderStyle.Render("┌ Profiles "))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-12)))
+	s.WriteString(borderStyle.Render("┐"))
+	s.WriteString("\n")
+
+	// Table header
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render(fmt.Sprintf("%-5s", "")))
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render(fmt.Sprintf("%-40s", "Profile")))
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render(fmt.Sprintf("%-10s", "URLs")))
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render(fmt.Sprintf("%-12s", "Mode")))
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render(fmt.Sprintf("%-41s", "Default duration")))
+	s.WriteString(borderStyle.Render(" │"))
+	s.WriteString("\n")
+
+	// Separator
+	s.WriteString(borderStyle.Render("├──────┼"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", 41)))
+	s.WriteString(borderStyle.Render("┼"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", 11)))
+	s.WriteString(borderStyle.Render("┼"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", 13)))
+	s.WriteString(borderStyle.Render("┼"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", 43)))
+	s.WriteString(borderStyle.Render("┤"))
+	s.WriteString("\n")
+
+	// Profiles
+	for i, p := range m.state.Profiles {
+		cursor := "  "
+		lineStyle := lipgloss.NewStyle()
+
+		if i == m.cursor {
+			cursor = "▶ "
+			lineStyle = lineStyle.Background(highlightBg).Foreground(lipgloss.Color("117"))
+		} else if i%2 == 0 {
+			lineStyle = lineStyle.Background(selectedBg)
+		}
+
+		name := p.Name
+		if p.Name == m.state.CurrentProfile {
+			name += " (current)"
+		}
+		if len(name) > 40 {
+			name = name[:37] + "..."
+		}
+
+		def := "-"
+		if d := p.Default(); d > 0 {
+			def = timer.FormatDuration(d)
+		}
+
+		mode := "blocklist"
+		if p.Allowlist() {
+			mode = "allowlist"
+		}
+
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(lineStyle.Render(fmt.Sprintf("%-5s", cursor)))
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(lineStyle.Render(fmt.Sprintf("%-40s", name)))
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(lineStyle.Render(fmt.Sprintf("%-10d", len(p.URLs))))
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(lineStyle.Render(fmt.Sprintf("%-12s", mode)))
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(lineStyle.Render(fmt.Sprintf("%-41s", def)))
+		s.WriteString(borderStyle.Render(" │"))
+		s.WriteString("\n")
+	}
+
+	// Bottom border
+	s.WriteString(borderStyle.Render("└"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-2)))
+	s.WriteString(borderStyle.Render("┘"))
+	s.WriteString("\n\n")
+
+	// Command bar
+	cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	cmdKeyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
+
+	s.WriteString(cmdKeyStyle.Render("Enter") + " " + cmdStyle.Render("Switch"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("n") + " " + cmdStyle.Render("New"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("m") + " " + cmdStyle.Render("Blocklist/Allowlist"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("x") + " " + cmdStyle.Render("Delete"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("j,↓") + " " + cmdStyle.Render("Down"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("k,↑") + " " + cmdStyle.Render("Up"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Esc") + " " + cmdStyle.Render("Back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
+
+// renderNewProfileView renders the prompt for a new profile's name
+func (m Model) renderNewProfileView() string {
+	var s strings.Builder
+
+	borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("142"))
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("184")).Bold(true)
+
+	const tableWidth = 120
+
+	s.WriteString(borderStyle.Render("┌ New Profile "))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-15)))
+	s.WriteString(borderStyle.Render("┐"))
+	s.WriteString("\n")
+
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render("Profile name: "))
+	s.WriteString(m.textInput.View())
+	s.WriteString("\n")
+
+	s.WriteString(borderStyle.Render("└"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-2)))
+	s.WriteString(borderStyle.Render("┘"))
+	s.WriteString("\n\n")
+
+	// Command bar
+	cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	cmdKeyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
+
+	s.WriteString(cmdKeyStyle.Render("Enter") + " " + cmdStyle.Render("Create"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Esc") + " " + cmdStyle.Render("Cancel"))
+	s.WriteString("\n")
+
+	return s.String()
+}
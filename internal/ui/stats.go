@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phil/selfcontrol/internal/state"
+	"github.com/phil/selfcontrol/internal/stats"
+)
+
+// Ranges shown in the stats view
+const (
+	statsDays    = 7
+	statsWeeks   = 4
+	statsDomains = 5
+)
+
+// loadStats summarizes the session history for the stats view
+func (m *Model) loadStats() {
+	sessions, err := m.service.Sessions()
+	if err != nil {
+		m.err = err
+		return
+	}
+	sum := stats.Summarize(sessions, time.Now(), statsDays, statsWeeks, statsDomains)
+	m.stats = &sum
+}
+
+// handleStatsKeys processes keys in the stats view
+func (m Model) handleStatsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "h":
+		m.mode = viewMain
+		return m, nil
+
+	case "r":
+		m.loadStats()
+		return m, nil
+	}
+
+	return m, nil
+}
+
+// renderStatsView renders focus statistics from the session history
+func (m Model) renderStatsView() string {
+	var s strings.Builder
+
+	borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("142"))
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("184")).Bold(true)
+	barStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BB7D"))
+	inactiveStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	const tableWidth = 120
+	const contentWidth = tableWidth - 4
+
+	title := func(name string) {
+		s.WriteString(borderStyle.Render("┌ " + name + " "))
+		s.WriteString(borderStyle.Render(strings.Repeat("─", max(tableWidth-lipgloss.Width(name)-4, 0))))
+		s.WriteString(borderStyle.Render("┐"))
+		s.WriteString("\n")
+	}
+	row := func(text string, style lipgloss.Style) {
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(style.Render(fmt.Sprintf("%-*s", contentWidth, text)))
+		s.WriteString(borderStyle.Render(" │"))
+		s.WriteString("\n")
+	}
+	bottom := func() {
+		s.WriteString(borderStyle.Render("└"))
+		s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-2)))
+		s.WriteString(borderStyle.Render("┘"))
+		s.WriteString("\n\n")
+	}
+
+	sum := m.stats
+	if sum == nil {
+		sum = &stats.Summary{}
+	}
+
+	// Overview
+	title("Statistics")
+	row(fmt.Sprintf("Total focused: %s  │  Sessions: %d  │  Longest streak: %s  │  Current streak: %s",
+		formatHours(sum.Total), sum.Sessions, plural(sum.LongestStreak, "day"), plural(sum.CurrentStreak, "day")), headerStyle)
+	row(fmt.Sprintf("Ended: %d expired, %d cancelled, %d tampered",
+		sum.Ended[state.EndExpired], sum.Ended[state.EndCancelled], sum.Ended[state.EndTampered]), lipgloss.NewStyle())
+	bottom()
+
+	// Focused time per day and week, with bars scaled to the busiest period
+	periods := func(name, layout string, list []stats.Period) {
+		title(name)
+		var most time.Duration
+		for _, p := range list {
+			most = max(most, p.Focused)
+		}
+		for _, p := range list {
+			bar := ""
+			if most > 0 {
+				bar = strings.Repeat("█", int(float64(p.Focused)/float64(most)*60))
+			}
+			label := fmt.Sprintf("%-14s %10s  ", p.Start.Format(layout), formatHours(p.Focused))
+			s.WriteString(borderStyle.Render("│ "))
+			s.WriteString(label)
+			s.WriteString(barStyle.Render(fmt.Sprintf("%-*s", contentWidth-len(label), bar)))
+			s.WriteString(borderStyle.Render(" │"))
+			s.WriteString("\n")
+		}
+		bottom()
+	}
+	periods("Focused per Day", "Mon Jan 02", sum.Days)
+	periods("Focused per Week", "Week of Jan 02", sum.Weeks)
+
+	// Most blocked domains
+	title("Most Blocked")
+	if len(sum.TopDomains) == 0 {
+		row("(no sessions recorded yet)", inactiveStyle)
+	}
+	for _, d := range sum.TopDomains {
+		domain := d.Domain
+		if len(domain) > 80 {
+			domain = domain[:77] + "..."
+		}
+		row(fmt.Sprintf("%-80s %s", domain, plural(d.Sessions, "session")), lipgloss.NewStyle())
+	}
+	bottom()
+
+	// Command bar
+	cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	cmdKeyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
+
+	s.WriteString(cmdKeyStyle.Render("r") + " " + cmdStyle.Render("Refresh"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Esc") + " " + cmdStyle.Render("Back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
+
+// formatHours formats a duration as fractional hours
+func formatHours(d time.Duration) string {
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
+
+// plural formats a count of things
+func plural(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -8,8 +9,9 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/phil/selfcontrol/internal/blocker"
+	"github.com/phil/selfcontrol/internal/control"
 	"github.com/phil/selfcontrol/internal/state"
+	"github.com/phil/selfcontrol/internal/stats"
 	"github.com/phil/selfcontrol/internal/timer"
 )
 
@@ -21,6 +23,13 @@ const (
 	viewAddURL
 	viewDelete
 	viewSelectDuration
+	viewProfiles
+	viewNewProfile
+	viewStats
+	viewCustomDuration
+	viewUnlock
+	viewImport
+	viewExport
 )
 
 // Model represents the UI state
@@ -34,46 +43,68 @@ type Model struct {
 	quitting        bool
 	lastTickTime    time.Time
 	permissionError bool
+	refreshErr      error
+	service         control.Service
+	stats           *stats.Summary
+	imported        *importResult
+	export          exportOptions
+
+	// challenge is the emergency unlock challenge to type; the state only
+	// holds its hash
+	challenge string
 }
 
+// urlPlaceholder is shown in the empty URL input
+const urlPlaceholder = "example.com or *.example.*"
+
 // tickMsg is sent every second to update the timer
 type tickMsg time.Time
 
-// New creates a new UI model
-func New() (*Model, error) {
-	// Load state
-	st, err := state.Load()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load state: %w", err)
-	}
-
+// New creates a new UI model that reads and changes state through svc
+func New(svc control.Service) (*Model, error) {
 	// Create text input for URL entry
 	ti := textinput.New()
-	ti.Placeholder = "example.com or *.example.*"
+	ti.Placeholder = urlPlaceholder
 	ti.Focus()
 	ti.CharLimit = 200
 	ti.Width = 50
 
 	m := &Model{
-		state:          st,
 		mode:           viewMain,
 		textInput:      ti,
 		deleteSelected: make(map[int]bool),
 		lastTickTime:   time.Now(),
+		service:        svc,
 	}
 
-	// Check if session expired and clean up
-	if st.ActiveSession != nil && !st.IsSessionActive() {
-		// Session expired, unblock
-		if err := blocker.Unblock(); err != nil {
-			m.permissionError = true
-			m.err = fmt.Errorf("session expired but failed to unblock: %w", err)
+	// Load state, cleaning up an expired session
+	m.refresh()
+
+	return m, nil
+}
+
+// refresh reloads the state from the service
+// Failures keep the last known state; the permission error screen is only
+// shown if the daemon can't be reached for lack of permission, other errors
+// are shown until a refresh succeeds again.
+func (m *Model) refresh() {
+	st, err := m.service.Status()
+	if err != nil {
+		m.permissionError = control.IsPermission(err)
+		m.err = err
+		m.refreshErr = err
+		if m.state == nil {
+			m.state = &state.AppState{}
 		}
-		st.EndSession()
-		state.Save(st)
+		return
 	}
 
-	return m, nil
+	if m.refreshErr != nil && m.err == m.refreshErr {
+		m.err = nil
+	}
+	m.refreshErr = nil
+	m.permissionError = false
+	m.state = st
 }
 
 // Init initializes the UI
@@ -101,17 +132,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Update timer
 		m.lastTickTime = time.Time(msg)
 
-		// Check if session expired
-		if m.state.ActiveSession != nil && !m.state.IsSessionActive() {
-			// Session expired, unblock
-			if err := blocker.Unblock(); err != nil {
-				m.permissionError = true
-				m.err = fmt.Errorf("failed to unblock after timer expiry: %w", err)
-			} else {
-				m.state.EndSession()
-				state.Save(m.state)
-			}
-		}
+		// Pick up changes made elsewhere, such as the session expiring
+		m.refresh()
 
 		return m, tickCmd()
 
@@ -124,6 +146,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // handleKeyPress processes keyboard input
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// The locked list explanation is dismissed by the next key
+	if errors.Is(m.err, state.ErrSessionLocked) || errors.Is(m.err, state.ErrAllowlistLocked) {
+		m.err = nil
+	}
+
 	switch m.mode {
 	case viewMain:
 		return m.handleMainKeys(msg)
@@ -133,6 +160,20 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleDeleteKeys(msg)
 	case viewSelectDuration:
 		return m.handleDurationKeys(msg)
+	case viewProfiles:
+		return m.handleProfileKeys(msg)
+	case viewNewProfile:
+		return m.handleNewProfileKeys(msg)
+	case viewStats:
+		return m.handleStatsKeys(msg)
+	case viewCustomDuration:
+		return m.handleCustomDurationKeys(msg)
+	case viewUnlock:
+		return m.handleUnlockKeys(msg)
+	case viewImport:
+		return m.handleImportKeys(msg)
+	case viewExport:
+		return m.handleExportKeys(msg)
 	}
 	return m, nil
 }
@@ -145,6 +186,12 @@ func (m Model) handleMainKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 
 	case "a":
+		// Allowlists of the running session can't grow
+		if m.state.IsLocked(m.state.CurrentProfile) && m.state.ActiveSession.Allowlist() {
+			m.err = state.ErrAllowlistLocked
+			return m, nil
+		}
+
 		// Enter add URL mode
 		m.mode = viewAddURL
 		m.textInput.SetValue("")
@@ -153,59 +200,111 @@ func (m Model) handleMainKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "up", "k":
 		// Navigate up in URL list
-		if len(m.state.URLs) > 0 && m.cursor > 0 {
+		if len(m.urls()) > 0 && m.cursor > 0 {
 			m.cursor--
 		}
 		return m, nil
 
 	case "down", "j":
 		// Navigate down in URL list
-		if len(m.state.URLs) > 0 && m.cursor < len(m.state.URLs)-1 {
+		if len(m.urls()) > 0 && m.cursor < len(m.urls())-1 {
 			m.cursor++
 		}
 		return m, nil
 
 	case "d":
 		// Delete currently selected URL
-		if len(m.state.URLs) > 0 && m.cursor < len(m.state.URLs) {
-			m.state.RemoveURLs([]int{m.cursor})
-			if err := state.Save(m.state); err != nil {
+		if len(m.urls()) > 0 && m.cursor < len(m.urls()) {
+			if err := m.service.RemoveURLs([]string{m.urls()[m.cursor]}); err != nil {
 				m.err = err
 			}
+			m.refresh()
 			// Adjust cursor if needed
-			if m.cursor >= len(m.state.URLs) && len(m.state.URLs) > 0 {
-				m.cursor = len(m.state.URLs) - 1
+			if m.cursor >= len(m.urls()) && len(m.urls()) > 0 {
+				m.cursor = len(m.urls()) - 1
 			}
-			if len(m.state.URLs) == 0 {
+			if len(m.urls()) == 0 {
 				m.cursor = 0
 			}
 		}
 		return m, nil
 
 	case "s":
-		// Start blocking session
-		if len(m.state.URLs) > 0 && !m.state.IsSessionActive() {
+		// Start blocking session; an empty allowlist blocks everything
+		if m.canStart() {
 			m.mode = viewSelectDuration
-			m.cursor = 0
+			m.cursor = m.defaultDurationIndex()
 		}
 		return m, nil
+
+	case "i":
+		// Import blocklist files
+		if m.state.IsLocked(m.state.CurrentProfile) && m.state.ActiveSession.Allowlist() {
+			m.err = state.ErrAllowlistLocked
+			return m, nil
+		}
+		m.mode = viewImport
+		m.imported = nil
+		m.textInput.SetValue("")
+		m.textInput.Placeholder = importPlaceholder
+		m.textInput.Focus()
+		return m, nil
+
+	case "e":
+		// Export profiles to a file
+		m.mode = viewExport
+		m.export.written = ""
+		m.textInput.SetValue("")
+		m.textInput.Placeholder = exportPlaceholder
+		m.textInput.Focus()
+		return m, nil
+
+	case "p":
+		// Switch profiles
+		m.mode = viewProfiles
+		m.cursor = m.currentProfileIndex()
+		return m, nil
+
+	case "u":
+		// Emergency unlock of the active session
+		if m.state.IsSessionActive() {
+			return m.startUnlock()
+		}
+		return m, nil
+
+	case "h":
+		// Show statistics from the session history
+		m.mode = viewStats
+		m.loadStats()
+		return m, nil
 	}
 
 	return m, nil
 }
 
+// urls returns the URLs of the current profile
+func (m Model) urls() []string {
+	return m.state.Current().URLs
+}
+
+// canStart reports whether a session can be started from the current profile
+func (m Model) canStart() bool {
+	p := m.state.Current()
+	return !m.state.IsSessionActive() && (len(m.urls()) > 0 || p.Allowlist() || len(p.Subscriptions) > 0)
+}
+
 // handleAddURLKeys processes keys in add URL view
 func (m Model) handleAddURLKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "enter":
 		url := strings.TrimSpace(m.textInput.Value())
 		if url != "" {
-			m.state.AddURL(url)
-			if err := state.Save(m.state); err != nil {
+			if err := m.service.AddURL(url); err != nil {
 				m.err = err
 			}
+			m.refresh()
 			// Set cursor to the newly added URL (last item)
-			m.cursor = len(m.state.URLs) - 1
+			m.cursor = len(m.urls()) - 1
 		}
 		m.mode = viewMain
 		return m, nil
@@ -235,7 +334,7 @@ func (m Model) handleDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "down", "j":
-		if m.cursor < len(m.state.URLs)-1 {
+		if m.cursor < len(m.urls())-1 {
 			m.cursor++
 		}
 		return m, nil
@@ -247,18 +346,18 @@ func (m Model) handleDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "enter":
 		// Delete selected URLs
-		var toDelete []int
+		var toDelete []string
 		for idx := range m.deleteSelected {
-			if m.deleteSelected[idx] {
-				toDelete = append(toDelete, idx)
+			if m.deleteSelected[idx] && idx < len(m.urls()) {
+				toDelete = append(toDelete, m.urls()[idx])
 			}
 		}
 
 		if len(toDelete) > 0 {
-			m.state.RemoveURLs(toDelete)
-			if err := state.Save(m.state); err != nil {
+			if err := m.service.RemoveURLs(toDelete); err != nil {
 				m.err = err
 			}
+			m.refresh()
 		}
 
 		m.mode = viewMain
@@ -285,33 +384,96 @@ func (m Model) handleDurationKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "down", "j":
-		if m.cursor < len(durations)-1 {
+		// The last entry is "Custom…"
+		if m.cursor < len(durations) {
 			m.cursor++
 		}
 		return m, nil
 
 	case "enter":
+		if m.cursor == len(durations) {
+			// Ask for a custom duration
+			m.mode = viewCustomDuration
+			m.textInput.SetValue("")
+			m.textInput.Placeholder = customDurationPlaceholder
+			m.textInput.Focus()
+			return m, nil
+		}
+
 		// Start blocking session
 		selected := durations[m.cursor]
-		m.state.StartSession(selected.Duration, selected.Label)
+		if err := m.service.StartSession(selected.Duration, selected.Label, nil); err != nil {
+			m.err = err
+		}
+		m.refresh()
+
+		m.mode = viewMain
+		m.cursor = 0
+		return m, nil
 
-		// Apply blocking
-		if err := blocker.Block(m.state.URLs); err != nil {
-			m.permissionError = true
-			m.err = fmt.Errorf("failed to apply blocking: %w", err)
-			m.state.EndSession()
+	case "D":
+		// Make the highlighted duration the profile's default
+		if m.cursor == len(durations) {
+			return m, nil
+		}
+		if err := m.service.SetDefaultDuration(m.state.CurrentProfile, durations[m.cursor].Duration); err != nil {
+			m.err = err
 		}
+		m.refresh()
+		return m, nil
+	}
+
+	return m, nil
+}
+
+// customDurationPlaceholder is shown in the empty custom duration input
+const customDurationPlaceholder = "90m, 2h30m or until 17:30"
 
-		if err := state.Save(m.state); err != nil {
+// handleCustomDurationKeys processes keys while entering a custom duration
+func (m Model) handleCustomDurationKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		d, err := timer.Parse(m.textInput.Value(), time.Now().In(timer.LocalZone()))
+		if err != nil {
+			// Stay in the view so the input can be corrected
+			m.err = err
+			return m, nil
+		}
+
+		m.err = nil
+		if err := m.service.StartSessionUntil(d.End, d.Label, nil); err != nil {
 			m.err = err
 		}
+		m.refresh()
 
+		m.textInput.Placeholder = urlPlaceholder
 		m.mode = viewMain
 		m.cursor = 0
 		return m, nil
+
+	case "esc":
+		m.err = nil
+		m.textInput.Placeholder = urlPlaceholder
+		m.mode = viewSelectDuration
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
 	}
+}
 
-	return m, nil
+// defaultDurationIndex returns the index of the current profile's default
+// duration in the duration list, or 0
+func (m Model) defaultDurationIndex() int {
+	def := m.state.Current().Default()
+	for i, d := range timer.PredefinedDurations() {
+		if d.Duration == def {
+			return i
+		}
+	}
+	return 0
 }
 
 // View renders the UI
@@ -348,8 +510,21 @@ func (m Model) View() string {
 		return s.String()
 	}
 
-	// Show errors
-	if m.err != nil {
+	// Explain why the list can't be shortened, which isn't a failure
+	if errors.Is(m.err, state.ErrSessionLocked) {
+		lockedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C7AC75")).Bold(true)
+		s.WriteString(lockedStyle.Render("🔒 The list is locked while the session runs"))
+		s.WriteString("\n")
+		s.WriteString("URLs can't be removed from a profile the active session enforces, so a block can't be undone\n")
+		s.WriteString("by editing the list. New URLs can still be added and are blocked right away.\n\n")
+	} else if errors.Is(m.err, state.ErrAllowlistLocked) {
+		lockedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C7AC75")).Bold(true)
+		s.WriteString(lockedStyle.Render("🔒 The allowlist is locked while the session runs"))
+		s.WriteString("\n")
+		s.WriteString("URLs can't be added to an allowlist the active session enforces, since every addition would\n")
+		s.WriteString("unblock more. URLs can still be removed and are blocked right away.\n\n")
+	} else if m.err != nil {
+		// Show errors
 		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 		s.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
 		s.WriteString("\n\n")
@@ -364,6 +539,20 @@ func (m Model) View() string {
 		s.WriteString(m.renderDeleteView())
 	case viewSelectDuration:
 		s.WriteString(m.renderDurationView())
+	case viewProfiles:
+		s.WriteString(m.renderProfilesView())
+	case viewNewProfile:
+		s.WriteString(m.renderNewProfileView())
+	case viewStats:
+		s.WriteString(m.renderStatsView())
+	case viewCustomDuration:
+		s.WriteString(m.renderCustomDurationView())
+	case viewUnlock:
+		s.WriteString(m.renderUnlockView())
+	case viewImport:
+		s.WriteString(m.renderImportView())
+	case viewExport:
+		s.WriteString(m.renderExportView())
 	}
 
 	return s.String()
@@ -375,6 +564,7 @@ func (m Model) renderMainView() string {
 
 	// Define colors - custom hex colors for each section
 	urlsBorderColor := lipgloss.Color("#A3BB7D")    // Green for Blocked URLs
+	allowBorderColor := lipgloss.Color("#7DA3BB")   // Blue for Allowed URLs
 	sessionBorderColor := lipgloss.Color("#A69D88") // Tan for Session Status
 	inactiveColor := lipgloss.Color("240")          // Gray
 	highlightBg := lipgloss.Color("237")            // Dark gray for selection
@@ -383,12 +573,19 @@ func (m Model) renderMainView() string {
 	const tableWidth = 120
 	const urlColumnWidth = tableWidth - 4 // Account for borders and padding
 
-	// Blocked URLs Section
+	// Blocked URLs Section, or Allowed URLs for an allowlist profile
+	urlsTitle := fmt.Sprintf("┌ Blocked URLs · %s ", m.state.CurrentProfile)
+	emptyText := "(no URLs added yet - press 'a' to add)"
+	if m.state.Current().Allowlist() {
+		urlsBorderColor = allowBorderColor
+		urlsTitle = fmt.Sprintf("┌ Allowed URLs · %s · allowlist: everything else is blocked ", m.state.CurrentProfile)
+		emptyText = "(no URLs allowed yet - a session would block everything; press 'a' to add)"
+	}
 	urlsBorderStyle := lipgloss.NewStyle().Foreground(urlsBorderColor)
 	urlsHeaderStyle := lipgloss.NewStyle().Foreground(urlsBorderColor).Bold(true)
 
-	s.WriteString(urlsBorderStyle.Render("┌ Blocked URLs "))
-	s.WriteString(urlsBorderStyle.Render(strings.Repeat("─", tableWidth-16)))
+	s.WriteString(urlsBorderStyle.Render(urlsTitle))
+	s.WriteString(urlsBorderStyle.Render(strings.Repeat("─", max(tableWidth-lipgloss.Width(urlsTitle)-1, 0))))
 	s.WriteString(urlsBorderStyle.Render("┐"))
 	s.WriteString("\n")
 
@@ -405,14 +602,15 @@ func (m Model) renderMainView() string {
 	s.WriteString("\n")
 
 	// URLs or empty message
-	if len(m.state.URLs) == 0 {
-		emptyMsg := lipgloss.NewStyle().Foreground(inactiveColor).Render("(no URLs added yet - press 'a' to add)")
+	subscriptions := m.state.Current().Subscriptions
+	if len(m.urls()) == 0 && len(subscriptions) == 0 {
+		emptyMsg := lipgloss.NewStyle().Foreground(inactiveColor).Render(emptyText)
 		s.WriteString(urlsBorderStyle.Render("│ "))
 		s.WriteString(fmt.Sprintf("%-*s", urlColumnWidth, emptyMsg))
 		s.WriteString(urlsBorderStyle.Render(" │"))
 		s.WriteString("\n")
 	} else {
-		for i, url := range m.state.URLs {
+		for i, url := range m.urls() {
 			// Truncate URL if too long
 			displayURL := url
 			if len(displayURL) > urlColumnWidth-6 {
@@ -441,6 +639,20 @@ func (m Model) renderMainView() string {
 		}
 	}
 
+	// Subscribed lists, which are managed with selfcontrol subscribe
+	subscriptionStyle := lipgloss.NewStyle().Foreground(inactiveColor)
+	for _, sub := range subscriptions {
+		line := "  ↻ " + sub.URL + " (subscribed list)"
+		if lipgloss.Width(line) > urlColumnWidth {
+			line = line[:urlColumnWidth-1] + "..."
+		}
+		line += strings.Repeat(" ", max(urlColumnWidth-lipgloss.Width(line), 0))
+		s.WriteString(urlsBorderStyle.Render("│ "))
+		s.WriteString(subscriptionStyle.Render(line))
+		s.WriteString(urlsBorderStyle.Render(" │"))
+		s.WriteString("\n")
+	}
+
 	// Bottom border
 	s.WriteString(urlsBorderStyle.Render("└"))
 	s.WriteString(urlsBorderStyle.Render(strings.Repeat("─", tableWidth-2)))
@@ -460,16 +672,38 @@ func (m Model) renderMainView() string {
 		elapsed := time.Since(m.state.ActiveSession.StartTime)
 
 		// Status message
-		statusMsg := fmt.Sprintf("🔒 ACTIVE  │  Time Remaining: %s  │  Elapsed: %s  │  Duration: %s",
+		status := "🔒 ACTIVE"
+		if m.state.ActiveSession.Allowlist() {
+			status = "🔒 ALLOWLIST"
+		}
+		statusMsg := fmt.Sprintf("%s  │  Time Remaining: %s  │  Elapsed: %s  │  Duration: %s  │  Profile: %s",
+			status,
 			timer.FormatDuration(remaining),
 			timer.FormatDuration(elapsed),
-			m.state.ActiveSession.Duration)
+			m.state.ActiveSession.Duration,
+			strings.Join(m.state.ActiveSession.Profiles, ", "))
 
 		s.WriteString(sessionBorderStyle.Render("│ "))
 		activeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C7AC75")).Bold(true)
 		s.WriteString(activeStyle.Render(fmt.Sprintf("%-*s", urlColumnWidth, statusMsg)))
 		s.WriteString(sessionBorderStyle.Render(" │"))
 		s.WriteString("\n")
+
+		if m.state.ActiveSession.Allowlist() {
+			modeMsg := fmt.Sprintf("Everything is blocked except %s", plural(len(m.state.SessionURLs()), "allowed URL"))
+			s.WriteString(sessionBorderStyle.Render("│ "))
+			s.WriteString(activeStyle.Render(fmt.Sprintf("%-*s", urlColumnWidth, modeMsg)))
+			s.WriteString(sessionBorderStyle.Render(" │"))
+			s.WriteString("\n")
+		}
+
+		if remaining, ok := m.state.UnlockRemaining(); ok {
+			unlockMsg := fmt.Sprintf("⏳ Emergency unlock in %s - press 'u' to withdraw it or see details", timer.FormatDuration(remaining))
+			s.WriteString(sessionBorderStyle.Render("│ "))
+			s.WriteString(activeStyle.Render(fmt.Sprintf("%-*s", urlColumnWidth, unlockMsg)))
+			s.WriteString(sessionBorderStyle.Render(" │"))
+			s.WriteString("\n")
+		}
 	} else {
 		s.WriteString(sessionBorderStyle.Render("│ "))
 		inactiveStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C7AC75"))
@@ -487,18 +721,34 @@ func (m Model) renderMainView() string {
 	cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
 	cmdKeyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
 
+	// A locked blocklist can only grow and a locked allowlist only shrink
+	locked := m.state.IsLocked(m.state.CurrentProfile)
+	allowlist := locked && m.state.ActiveSession.Allowlist()
+
 	commands := []string{}
-	commands = append(commands, cmdKeyStyle.Render("a")+" "+cmdStyle.Render("Add"))
+	if !allowlist {
+		commands = append(commands, cmdKeyStyle.Render("a")+" "+cmdStyle.Render("Add"))
+		commands = append(commands, cmdKeyStyle.Render("i")+" "+cmdStyle.Render("Import"))
+	}
 
-	if len(m.state.URLs) > 0 {
-		commands = append(commands, cmdKeyStyle.Render("d")+" "+cmdStyle.Render("Delete"))
+	if len(m.urls()) > 0 {
+		if !locked || allowlist {
+			commands = append(commands, cmdKeyStyle.Render("d")+" "+cmdStyle.Render("Delete"))
+		}
 		commands = append(commands, cmdKeyStyle.Render("↑/↓")+" "+cmdStyle.Render("Navigate"))
 	}
 
-	if len(m.state.URLs) > 0 && !m.state.IsSessionActive() {
+	if m.canStart() {
 		commands = append(commands, cmdKeyStyle.Render("s")+" "+cmdStyle.Render("Start"))
 	}
 
+	if m.state.IsSessionActive() {
+		commands = append(commands, cmdKeyStyle.Render("u")+" "+cmdStyle.Render("Unlock"))
+	}
+
+	commands = append(commands, cmdKeyStyle.Render("e")+" "+cmdStyle.Render("Export"))
+	commands = append(commands, cmdKeyStyle.Render("p")+" "+cmdStyle.Render("Profiles"))
+	commands = append(commands, cmdKeyStyle.Render("h")+" "+cmdStyle.Render("Stats"))
 	commands = append(commands, cmdKeyStyle.Render("q")+" "+cmdStyle.Render("Quit"))
 
 	s.WriteString(strings.Join(commands, " │ "))
@@ -507,6 +757,58 @@ func (m Model) renderMainView() string {
 	return s.String()
 }
 
+// renderCustomDurationView renders the prompt for a custom duration
+func (m Model) renderCustomDurationView() string {
+	var s strings.Builder
+
+	borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("142"))
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("184")).Bold(true)
+	exampleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	const tableWidth = 120
+	const contentWidth = tableWidth - 4
+
+	s.WriteString(borderStyle.Render("┌ Custom Duration "))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-19)))
+	s.WriteString(borderStyle.Render("┐"))
+	s.WriteString("\n")
+
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(headerStyle.Render("Block for: "))
+	s.WriteString(m.textInput.View())
+	s.WriteString("\n")
+
+	examples := []string{
+		"  90m                   - 1 hour 30 minutes",
+		"  2h30m                 - 2 hours 30 minutes",
+		"  until 17:30           - Until the next 17:30",
+		"  until Friday 17:00    - Until a day and time (also today, tomorrow or a YYYY-MM-DD date)",
+		"  Any duration " + timer.DurationLimits,
+	}
+	for _, example := range examples {
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(exampleStyle.Render(fmt.Sprintf("%-*s", contentWidth, example)))
+		s.WriteString(borderStyle.Render(" │"))
+		s.WriteString("\n")
+	}
+
+	s.WriteString(borderStyle.Render("└"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-2)))
+	s.WriteString(borderStyle.Render("┘"))
+	s.WriteString("\n\n")
+
+	// Command bar
+	cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	cmdKeyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
+
+	s.WriteString(cmdKeyStyle.Render("Enter") + " " + cmdStyle.Render("Start"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Esc") + " " + cmdStyle.Render("Back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
+
 // renderAddURLView renders the add URL view
 func (m Model) renderAddURLView() string {
 	var s strings.Builder
@@ -621,7 +923,7 @@ func (m Model) renderDeleteView() string {
 	s.WriteString("\n")
 
 	// URLs
-	for i, url := range m.state.URLs {
+	for i, url := range m.urls() {
 		displayURL := url
 		if len(displayURL) > 100 {
 			displayURL = displayURL[:97] + "..."
@@ -740,6 +1042,9 @@ func (m Model) renderDurationView() string {
 		if description == "" {
 			description = "Custom duration"
 		}
+		if dur.Duration == m.state.Current().Default() {
+			description += " (profile default)"
+		}
 
 		s.WriteString(borderStyle.Render("│ "))
 		s.WriteString(lineStyle.Render(fmt.Sprintf("%-5s", cursor)))
@@ -751,6 +1056,24 @@ func (m Model) renderDurationView() string {
 		s.WriteString("\n")
 	}
 
+	// Custom entry
+	cursor := "  "
+	lineStyle := lipgloss.NewStyle()
+	if m.cursor == len(durations) {
+		cursor = "▶ "
+		lineStyle = lineStyle.Background(highlightBg).Foreground(lipgloss.Color("117"))
+	} else if len(durations)%2 == 0 {
+		lineStyle = lineStyle.Background(selectedBg)
+	}
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(lineStyle.Render(fmt.Sprintf("%-5s", cursor)))
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(lineStyle.Render("Custom…" + strings.Repeat(" ", 30-lipgloss.Width("Custom…"))))
+	s.WriteString(borderStyle.Render("│ "))
+	s.WriteString(lineStyle.Render(fmt.Sprintf("%-75s", "Enter a duration or end time, e.g. 90m or until 17:30")))
+	s.WriteString(borderStyle.Render(" │"))
+	s.WriteString("\n")
+
 	// Bottom border
 	s.WriteString(borderStyle.Render("└"))
 	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-2)))
@@ -763,6 +1086,8 @@ func (m Model) renderDurationView() string {
 
 	s.WriteString(cmdKeyStyle.Render("Enter") + " " + cmdStyle.Render("Start"))
 	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("D") + " " + cmdStyle.Render("Set default"))
+	s.WriteString(" │ ")
 	s.WriteString(cmdKeyStyle.Render("j,↓") + " " + cmdStyle.Render("Down"))
 	s.WriteString(" │ ")
 	s.WriteString(cmdKeyStyle.Render("k,↑") + " " + cmdStyle.Render("Up"))
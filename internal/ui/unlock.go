@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phil/selfcontrol/internal/timer"
+)
+
+// startUnlock requests an emergency unlock and shows the challenge
+func (m Model) startUnlock() (tea.Model, tea.Cmd) {
+	if _, pending := m.state.UnlockRemaining(); !pending {
+		challenge, err := m.service.RequestUnlock()
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.challenge = challenge
+		m.refresh()
+	}
+
+	m.mode = viewUnlock
+	m.textInput.SetValue("")
+	m.textInput.Placeholder = "type the text above"
+	m.textInput.Focus()
+	return m, nil
+}
+
+// handleUnlockKeys processes keys in the emergency unlock view
+func (m Model) handleUnlockKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	_, pending := m.state.UnlockRemaining()
+
+	switch msg.String() {
+	case "esc":
+		m.err = nil
+		m.textInput.Placeholder = urlPlaceholder
+		m.mode = viewMain
+		return m, nil
+
+	case "ctrl+x":
+		// Withdraw the unlock
+		if err := m.service.CancelUnlock(); err != nil {
+			m.err = err
+		}
+		m.challenge = ""
+		m.refresh()
+		m.textInput.Placeholder = urlPlaceholder
+		m.mode = viewMain
+		return m, nil
+
+	case "enter":
+		if pending {
+			return m, nil
+		}
+		if _, err := m.service.ConfirmUnlock(m.textInput.Value()); err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.err = nil
+		m.challenge = ""
+		m.refresh()
+		return m, nil
+	}
+
+	// The challenge has to be typed: pasted text arrives as several runes at
+	// once and is ignored
+	if pending || len(msg.Runes) > 1 {
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+// renderUnlockView renders the emergency unlock challenge or countdown
+func (m Model) renderUnlockView() string {
+	var s strings.Builder
+
+	borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("142"))
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("184")).Bold(true)
+	challengeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C7AC75")).Bold(true)
+	noteStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	const tableWidth = 120
+	const contentWidth = tableWidth - 4
+
+	row := func(text string, style lipgloss.Style) {
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(style.Render(fmt.Sprintf("%-*s", contentWidth, text)))
+		s.WriteString(borderStyle.Render(" │"))
+		s.WriteString("\n")
+	}
+
+	s.WriteString(borderStyle.Render("┌ Emergency Unlock "))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-20)))
+	s.WriteString(borderStyle.Render("┐"))
+	s.WriteString("\n")
+
+	remaining, pending := m.state.UnlockRemaining()
+	delay := timer.FormatDuration(m.state.GetUnlockDelay())
+
+	switch {
+	case pending:
+		row(fmt.Sprintf("Unblocking in %s", timer.FormatDuration(remaining)), challengeStyle)
+		row("", lipgloss.NewStyle())
+		row("The session ends when the countdown is over, even if this window is closed.", noteStyle)
+		row("Changed your mind? Withdraw the unlock with Ctrl+X and keep focusing.", noteStyle)
+
+	case m.state.ActiveSession != nil && m.state.ActiveSession.Unlock != nil && m.challenge != "":
+		row("To end the session early, type this text exactly:", lipgloss.NewStyle())
+		row("", lipgloss.NewStyle())
+		row(m.challenge, challengeStyle)
+		row("", lipgloss.NewStyle())
+		s.WriteString(borderStyle.Render("│ "))
+		s.WriteString(headerStyle.Render("Text: "))
+		s.WriteString(m.textInput.View())
+		s.WriteString("\n")
+		row("", lipgloss.NewStyle())
+		row(fmt.Sprintf("After that, the session is unblocked once a cooling-off period of %s has passed.", delay), noteStyle)
+		row("Every emergency unlock is recorded in the history.", noteStyle)
+
+	default:
+		row("No active session", noteStyle)
+	}
+
+	s.WriteString(borderStyle.Render("└"))
+	s.WriteString(borderStyle.Render(strings.Repeat("─", tableWidth-2)))
+	s.WriteString(borderStyle.Render("┘"))
+	s.WriteString("\n\n")
+
+	// Command bar
+	cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	cmdKeyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("117")).Bold(true)
+
+	if !pending {
+		s.WriteString(cmdKeyStyle.Render("Enter") + " " + cmdStyle.Render("Confirm"))
+		s.WriteString(" │ ")
+	}
+	s.WriteString(cmdKeyStyle.Render("Ctrl+X") + " " + cmdStyle.Render("Withdraw unlock"))
+	s.WriteString(" │ ")
+	s.WriteString(cmdKeyStyle.Render("Esc") + " " + cmdStyle.Render("Back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
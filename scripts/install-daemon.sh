@@ -28,6 +28,13 @@ if [[ "$OSTYPE" == "linux-gnu"* ]]; then
     # Stop service if already running
     systemctl stop selfcontrol-daemon 2>/dev/null || true
 
+    # Members of the selfcontrol group may use the daemon's control socket
+    getent group selfcontrol >/dev/null || groupadd --system selfcontrol
+    if [ -n "$SUDO_USER" ]; then
+        usermod -aG selfcontrol "$SUDO_USER"
+        echo "Added $SUDO_USER to the selfcontrol group (log in again to apply)"
+    fi
+
     # Copy service file
     cp scripts/selfcontrol-daemon.service /etc/systemd/system/
 
@@ -54,6 +61,13 @@ elif [[ "$OSTYPE" == "darwin"* ]]; then
     # Unload service if already running
     launchctl unload /Library/LaunchDaemons/com.selfcontrol.daemon.plist 2>/dev/null || true
 
+    # Members of the selfcontrol group may use the daemon's control socket
+    dseditgroup -o read selfcontrol >/dev/null 2>&1 || dseditgroup -o create selfcontrol
+    if [ -n "$SUDO_USER" ]; then
+        dseditgroup -o edit -a "$SUDO_USER" -t user selfcontrol
+        echo "Added $SUDO_USER to the selfcontrol group"
+    fi
+
     # Copy plist file
     cp scripts/com.selfcontrol.daemon.plist /Library/LaunchDaemons/
 